@@ -0,0 +1,12 @@
+// Package assets contains the default doccer theme.
+//
+// The filesystem is laid out the same way as a project's .doccer directory,
+// with a "templates" and a "static" directory at the root.
+package assets
+
+import "embed"
+
+// FS holds the default templates and static files.
+//
+//go:embed static templates
+var FS embed.FS
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
@@ -15,13 +16,16 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	_ "embed"
 
+	"github.com/Nigel2392/doccer/assets"
 	"github.com/Nigel2392/doccer/doccer/filesystem"
 	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/output"
 	"github.com/Nigel2392/typeutils/terminal"
 	"gopkg.in/yaml.v3"
 )
@@ -29,21 +33,14 @@ import (
 const DOCCER_DIR = ".doccer"
 const MAX_MENU_ITEMS_DEPTH = 1
 
-// FooterMenu represents the footer menu
+// FooterMenu holds the footer items added after the configured footer items,
+// unless disabled with hide_doccer_link.
 var FooterMenu = &Menu{
 	Items: []MenuItem{
 		{Name: "View Doccer on GitHub", URL: "https://github.com/Nigel2392/doccer"},
 	},
 }
 
-// Raise a panic with a message
-// Prefixes the message with "doccer: "
-func raise(message string) {
-	panic(
-		fmt.Sprintf("doccer: %s", message),
-	)
-}
-
 type Doccer struct {
 	// Config file path
 	configPath string
@@ -55,27 +52,119 @@ type Doccer struct {
 	features map[string]Feature
 
 	embedFS *DoccerFS
+
+	// Documentation input, nil if read from the input directory
+	docsFS fs.FS
+
+	// Set if the instance is mounted inside another server
+	mounted bool
+
+	// Authentication for the server, nil if disabled
+	auth *authenticator
+
+	// Options for the export command
+	exportOptions ExportOptions
+
+	// Options for the test command
+	testOptions TestOptions
+
+	// Options for the build command
+	buildOptions BuildOptions
+
+	// Command line arguments, parsed again when the config is reloaded
+	args []string
+
+	// Fingerprinted names of the static assets, set while building
+	assets map[string]string
+
+	// Icons which have been read and used
+	icons iconCache
+
+	// Tags of the loaded documentation tree
+	tagIndex tagIndex
+
+	// Files included by templates, used to find the pages to rebuild
+	includes includeIndex
+
+	// Headings of the rendered pages, used to resolve references
+	headings headingIndex
 }
 
 // NewDoccer creates a new doccer instance
-func NewDoccer(embedFS fs.FS, configPath string) (*Doccer, error) {
+//
+// The theme filesystem holds the default templates and static files,
+// these can be overridden per project in the .doccer directory.
+// Themes with the templates and static files inside of an assets directory are supported as well.
+func NewDoccer(theme fs.FS, configPath string) (*Doccer, error) {
 	var doccer = &Doccer{
 		configPath: configPath,
 		features:   make(map[string]Feature),
-		embedFS:    &DoccerFS{embedFS},
+		embedFS: &DoccerFS{
+			FS:        themeFS(theme),
+			Overrides: os.DirFS(DOCCER_DIR),
+		},
 	}
 	doccer.config = NewConfig(doccer)
 
 	return doccer, nil
 }
 
+// NewHandler creates a new doccer instance which can be used as a http.Handler
+// inside of an existing server.
+//
+// No configuration file or .doccer directory is used; the documentation is read from docs
+// and the templates and static files from theme. If theme is nil the default theme is used.
+//
+// The handler can be mounted under any prefix with http.StripPrefix,
+// config.Server.BaseURL should then be set to that prefix for links to resolve.
+// The config is copied, defaults are not set on the value passed in.
+func NewHandler(config *Config, docs fs.FS, theme fs.FS) (*Doccer, error) {
+	if config == nil {
+		return nil, errors.New("config is required")
+	}
+
+	if docs == nil {
+		return nil, errors.New("documentation filesystem is required")
+	}
+
+	if theme == nil {
+		theme = assets.FS
+	}
+
+	config = config.clone()
+
+	var doccer = &Doccer{
+		config:   config,
+		features: make(map[string]Feature),
+		embedFS:  &DoccerFS{FS: themeFS(theme)},
+		docsFS:   docs,
+		mounted:  true,
+	}
+
+	// Serve static files from below the mount point by default
+	if config.Server.StaticUrl == "" {
+		config.Server.StaticUrl = path.Join("/", config.Server.BaseURL, "static")
+	}
+
+	config.Instance = doccer
+
+	return doccer, doccer.setup()
+}
+
 // FS returns the filesystem
 func (d *Doccer) FS() *DoccerFS {
 	return d.embedFS
 }
 
+// SetInput reads the documentation from the filesystem instead of the
+// configured input directory. It must be called before Load.
+func (d *Doccer) SetInput(docs fs.FS) {
+	d.docsFS = docs
+}
+
 // ParseArgs parses the arguments for the command
 func (d *Doccer) ParseArgs(args []string) (err error) {
+	d.args = args
 	if len(args) == 0 {
 		return
 	}
@@ -115,17 +204,37 @@ func (d *Doccer) Load() error {
 		return ErrNoConfig
 	}
 
-	// Unmarshal the config
-	err = yaml.Unmarshal(yamlConfig, d.config)
+	// Unmarshal the config, the parsed document is kept to report invalid keys
+	var node yaml.Node
+	err = yaml.Unmarshal(yamlConfig, &node)
 	if err != nil {
 		return err
 	}
 
-	err = d.config.Init()
+	err = node.Decode(d.config)
 	if err != nil {
 		return err
 	}
 
+	d.config.node = &node
+
+	return d.setup()
+}
+
+// setup initializes the configuration and enabled features
+func (d *Doccer) setup() error {
+	var err = d.config.Init()
+	if err != nil {
+		return err
+	}
+
+	if d.config.Server.Auth != nil {
+		d.auth, err = newAuthenticator(d.config.Server.Auth)
+		if err != nil {
+			return err
+		}
+	}
+
 	var h = hooks.Get[FeatureHook]("register_features")
 	for _, hook := range h {
 		var feature = hook(d, d.config)
@@ -149,12 +258,25 @@ func (d *Doccer) Load() error {
 	return nil
 }
 
-// GetMenu returns the menu for the documentation
-func (d *Doccer) BuildMenu(isServing bool) *Menu {
+// BuildMenu returns the menu for the documentation
+func (d *Doccer) BuildMenu(isServing bool) (*Menu, error) {
 	var menu = &Menu{
 		Items: make([]MenuItem, 0),
 	}
 
+	if d.config.Menu != nil && d.config.Menu.Auto {
+		menu.Logo = d.config.Menu.Logo
+		menu.Auto = true
+		menu.Items = d.autoMenuItems(d.config.RootDirectory, isServing)
+
+		var h = hooks.Get[ConstructMenuHook]("construct_menu")
+		for _, hook := range h {
+			hook(d, menu)
+		}
+
+		return menu, nil
+	}
+
 	if d.config.Menu != nil && len(d.config.Menu.Items) > 0 {
 		return d.buildMenu(d.config.Menu, d.config.RootDirectory, isServing)
 	}
@@ -163,19 +285,21 @@ func (d *Doccer) BuildMenu(isServing bool) *Menu {
 		menu.Logo = d.config.Menu.Logo
 	}
 
-	if len(d.config.Menu.Items) == 0 {
+	if d.config.Menu == nil || len(d.config.Menu.Items) == 0 {
 		d.config.RootDirectory.Subdirectories.ForEach(func(key string, v *filesystem.TemplateDirectory) bool {
 			menu.Items = append(menu.Items, MenuItem{
-				Name: v.GetTitle(),
-				URL:  ObjectURL(d.config.Server.BaseURL, v, isServing),
+				Name:   v.GetTitle(),
+				URL:    ObjectURL(d.config.Server.BaseURL, v, isServing),
+				object: v,
 			})
 			return true
 		})
 
 		d.config.RootDirectory.Templates.ForEach(func(key string, v *filesystem.Template) bool {
 			menu.Items = append(menu.Items, MenuItem{
-				Name: v.GetTitle(),
-				URL:  ObjectURL(d.config.Server.BaseURL, v, isServing),
+				Name:   v.GetTitle(),
+				URL:    ObjectURL(d.config.Server.BaseURL, v, isServing),
+				object: v,
 			})
 			return true
 		})
@@ -186,26 +310,31 @@ func (d *Doccer) BuildMenu(isServing bool) *Menu {
 		hook(d, menu)
 	}
 
-	return menu
+	return menu, nil
 }
 
-func (d *Doccer) buildMenu(m *Menu, dir *filesystem.TemplateDirectory, isServing bool) *Menu {
-	var menu = &Menu{
-		Items: make([]MenuItem, 0),
-	}
+func (d *Doccer) buildMenu(m *Menu, dir *filesystem.TemplateDirectory, isServing bool) (*Menu, error) {
+	var (
+		menu = &Menu{
+			Logo: m.Logo,
+		}
+		err error
+	)
 
-	menu.Logo = m.Logo
-	menu.Items = d.buildMenuItems(m.Items, dir, isServing, 0)
+	menu.Items, err = d.buildMenuItems(m.Items, dir, isServing, 0)
+	if err != nil {
+		return nil, err
+	}
 
 	var h = hooks.Get[ConstructMenuHook]("construct_menu")
 	for _, hook := range h {
 		hook(d, menu)
 	}
 
-	return menu
+	return menu, nil
 }
 
-func (d *Doccer) buildMenuItems(m []MenuItem, dir *filesystem.TemplateDirectory, isServing bool, depth int) []MenuItem {
+func (d *Doccer) buildMenuItems(m []MenuItem, dir *filesystem.TemplateDirectory, isServing bool, depth int) ([]MenuItem, error) {
 	var items = make([]MenuItem, 0)
 	for i, item := range m {
 		var parts = strings.Split(item.URL, "/")
@@ -213,15 +342,17 @@ func (d *Doccer) buildMenuItems(m []MenuItem, dir *filesystem.TemplateDirectory,
 			parts = []string{}
 		}
 
-		var url string = item.URL
-		if !strings.HasPrefix(url, d.config.Server.BaseURL) {
-			url = path.Join(d.config.Server.BaseURL, url)
-		}
+		// External links are used as-is
+		var (
+			url    string = item.URL
+			object filesystem.Object
+		)
 		if IsLocal(item.URL) {
 			var obj, ok = dir.Walk(parts)
 			if !ok {
-				raise(fmt.Sprintf("menu item not found: %s", item.URL))
+				return nil, fmt.Errorf("menu item not found: %s", item.URL)
 			}
+			object = obj
 
 			if item.Name == "" {
 				item.Name = obj.GetTitle()
@@ -235,14 +366,19 @@ func (d *Doccer) buildMenuItems(m []MenuItem, dir *filesystem.TemplateDirectory,
 		}
 
 		if url == "" {
-			raise(fmt.Sprintf("menu item %d has no URL: %s", i, item.Name))
+			return nil, fmt.Errorf("menu item %d has no URL: %s", i, item.Name)
 		}
 
 		if len(item.Items) > 0 {
 			if depth > MAX_MENU_ITEMS_DEPTH {
-				raise(fmt.Sprintf("menu item %s has too many levels: %d > %d", item.Name, i, MAX_MENU_ITEMS_DEPTH))
+				return nil, fmt.Errorf("menu item %s has too many levels: %d > %d", item.Name, depth, MAX_MENU_ITEMS_DEPTH)
+			}
+
+			var err error
+			item.Items, err = d.buildMenuItems(item.Items, dir, isServing, depth+1)
+			if err != nil {
+				return nil, err
 			}
-			item.Items = d.buildMenuItems(item.Items, dir, isServing, depth+1)
 		}
 
 		items = append(items, MenuItem{
@@ -252,21 +388,120 @@ func (d *Doccer) buildMenuItems(m []MenuItem, dir *filesystem.TemplateDirectory,
 			Icon:       item.Icon,
 			Classname:  item.Classname,
 			Attributes: item.Attributes,
+			object:     object,
 		})
 	}
 
-	return items
+	return items, nil
+}
+
+// buildFooter returns the configured footer items followed by the FooterMenu items
+func (d *Doccer) buildFooter(isServing bool) (*Menu, error) {
+	var items, err = d.buildMenuItems(d.config.Footer.Items, d.config.RootDirectory, isServing, MAX_MENU_ITEMS_DEPTH)
+	if err != nil {
+		return nil, err
+	}
+
+	var footer = &Menu{
+		Logo:  FooterMenu.Logo,
+		Items: items,
+	}
+
+	if !d.config.Footer.HideDoccerLink {
+		for _, item := range FooterMenu.Items {
+			footer.Items = append(footer.Items, item.Copy())
+		}
+	}
+
+	return footer, nil
+}
+
+// autoMenuItems returns menu items mirroring the directory and all of its descendants.
+// Items with the Order directive come first sorted by their order,
+// objects without one follow in the order of the tree.
+func (d *Doccer) autoMenuItems(dir *filesystem.TemplateDirectory, isServing bool) []MenuItem {
+	type orderedItem struct {
+		order   int
+		ordered bool
+		item    MenuItem
+	}
+
+	var items = make([]orderedItem, 0)
+	dir.Subdirectories.ForEach(func(key string, v *filesystem.TemplateDirectory) bool {
+		items = append(items, orderedItem{
+			order:   v.GetOrder(),
+			ordered: v.IsOrdered(),
+			item: MenuItem{
+				Name:   v.GetTitle(),
+				URL:    ObjectURL(d.config.Server.BaseURL, v, isServing),
+				Items:  d.autoMenuItems(v, isServing),
+				object: v,
+			},
+		})
+		return true
+	})
+
+	dir.Templates.ForEach(func(key string, v *filesystem.Template) bool {
+		// Images and other assets are not pages
+		if !v.IsTextFile() {
+			return true
+		}
+		items = append(items, orderedItem{
+			order:   v.GetOrder(),
+			ordered: v.IsOrdered(),
+			item: MenuItem{
+				Name:   v.GetTitle(),
+				URL:    ObjectURL(d.config.Server.BaseURL, v, isServing),
+				object: v,
+			},
+		})
+		return true
+	})
+
+	slices.SortStableFunc(items, func(a, b orderedItem) int {
+		switch {
+		case a.ordered && b.ordered:
+			return a.order - b.order
+		case a.ordered:
+			return -1
+		case b.ordered:
+			return 1
+		}
+		return 0
+	})
+
+	var menuItems = make([]MenuItem, len(items))
+	for i, item := range items {
+		menuItems[i] = item.item
+	}
+	return menuItems
 }
 
 // GetContext returns the context for the documentation
-func (d *Doccer) GetContext(isServing bool) *Context {
+func (d *Doccer) GetContext(isServing bool) (*Context, error) {
+	var menu, err = d.BuildMenu(isServing)
+	if err != nil {
+		return nil, err
+	}
+
+	footer, err := d.buildFooter(isServing)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLinks, err := d.buildMenuItems(d.config.HeaderLinks, d.config.RootDirectory, isServing, MAX_MENU_ITEMS_DEPTH)
+	if err != nil {
+		return nil, err
+	}
+
 	var context = &Context{
-		isServing: isServing,
-		Ctx:       d.config.Context,
-		Tree:      make(map[string]interface{}),
-		Menu:      d.BuildMenu(isServing),
-		Footer:    FooterMenu,
-		Config:    d.config,
+		isServing:   isServing,
+		Ctx:         d.config.Context,
+		Tree:        make(map[string]interface{}),
+		Menu:        menu,
+		Footer:      footer,
+		HeaderLinks: headerLinks,
+		Config:      d.config,
 	}
 
 	context.Tree["root"] = &contextObject{
@@ -280,13 +515,14 @@ func (d *Doccer) GetContext(isServing bool) *Context {
 	d.config.RootDirectory.Subdirectories.ForEach(fnDirs)
 	d.config.RootDirectory.Templates.ForEach(fnTpls)
 
-	return context
+	return context, nil
 }
 
 var WidthRegex, _ = regexp.Compile(`width="([a-zA-Z0-9]+)"`)
 var HeightRegex, _ = regexp.Compile(`height="([a-zA-Z0-9]+)"`)
 
 func (d *Doccer) TemplateFuncs() template.FuncMap {
+	var include, snippet = d.includeFuncs(nil)
 	return template.FuncMap{
 		"JSON": func(v interface{}) template.HTML {
 			var b, err = json.MarshalIndent(v, "", "  ")
@@ -333,42 +569,18 @@ func (d *Doccer) TemplateFuncs() template.FuncMap {
 		},
 		// Returns an icon which can be used in markdown files.
 		// The regular "Icon" function can be used; but is not as good as it might mess up headings.
-		"MarkdownIcon": func(name string, alt ...string) string {
-			var altStr = name
-			if len(alt) > 1 {
-				altStr = strings.Join(alt, " ")
-			} else {
-				altStr = alt[0]
-			}
-			return fmt.Sprintf("![%s](%s)", altStr, d.AssetURL(path.Join(
-				"static/bootstrap-icons",
-				fmt.Sprintf("%s.svg", name),
-			)))
-		},
+		"MarkdownIcon": markdownIcon(d.AssetURL),
 		"Icon": func(name string, sizing ...string) template.HTML {
 			if name == "" {
 				return template.HTML("")
 			}
 
-			var w, h string
-			if len(sizing) == 1 {
-				var wh = strings.Split(sizing[0], "x")
-				if len(wh) == 2 {
-					w = wh[0]
-					h = wh[1]
-				} else {
-					w = sizing[0]
-					h = sizing[0]
-				}
-			} else if len(sizing) > 1 {
-				return template.HTML(fmt.Sprintf("Error: Icon sizing has too many arguments: %v", sizing))
-			} else {
-				w = "24"
-				h = "24"
+			var w, h, err = iconSize(sizing)
+			if err != nil {
+				return template.HTML(fmt.Sprintf("Error: %s", err))
 			}
 
-			var svgPath = fmt.Sprintf("static/bootstrap-icons/%s.svg", name)
-			var svg, err = fs.ReadFile(d.embedFS, svgPath)
+			svg, err := d.readIcon(name)
 			if err != nil {
 				return template.HTML(fmt.Sprintf("Error rendering SVG: %s", err))
 			}
@@ -382,11 +594,82 @@ func (d *Doccer) TemplateFuncs() template.FuncMap {
 		"Asset": func(name string) template.HTML {
 			return template.HTML(d.AssetURL(name))
 		},
+		"ChromaCSS": func(dark ...bool) (template.CSS, error) {
+			var css, err = d.chromaCSS(len(dark) > 0 && dark[0])
+			return template.CSS(css), err
+		},
+		"StaticIsLocal": func() bool {
+			return IsLocal(d.config.Server.StaticUrl)
+		},
+		"Ref": func(target string) (string, error) {
+			var obj, heading, err = d.lookupRef(target)
+			if err != nil {
+				return "", err
+			}
+			var url = ObjectURL(d.config.Server.BaseURL, obj, false)
+			if heading != nil {
+				url = fmt.Sprintf("%s#%s", url, heading.ID)
+			}
+			return url, nil
+		},
+		"PagesWithTag": func(name string) []filesystem.Object {
+			if tag, ok := d.Tag(tagSlug(name)); ok {
+				return tag.Pages
+			}
+			return nil
+		},
+		"Include":     include,
+		"Snippet":     snippet,
+		"IncludePage": d.includePage,
+	}
+}
+
+// contextFuncs returns the template functions bound to the context being rendered
+func (d *Doccer) contextFuncs(c *Context) template.FuncMap {
+	var funcs = d.TemplateFuncs()
+	funcs["MarkdownIcon"] = markdownIcon(c.AssetURL)
+	funcs["PagesWithTag"] = c.PagesWithTag
+	funcs["Ref"] = c.Ref
+	if d.iconSprite(c) {
+		funcs["Icon"] = d.spriteIcon(c)
+	}
+	funcs["Asset"] = func(name string) template.HTML {
+		return template.HTML(c.AssetURL(name))
+	}
+	return funcs
+}
+
+// executeTemplate executes a named template of the configured templates.
+// The templates are cloned first so the functions can be bound per execution.
+func (d *Doccer) executeTemplate(w io.Writer, name string, data interface{}, funcs template.FuncMap) error {
+	var tpl, err = d.config.Tpl.Clone()
+	if err != nil {
+		return err
+	}
+
+	if funcs != nil {
+		tpl.Funcs(funcs)
+	}
+
+	return tpl.ExecuteTemplate(w, name, data)
+}
+
+func markdownIcon(assetURL func(string) string) func(name string, alt ...string) string {
+	return func(name string, alt ...string) string {
+		var altStr = name
+		if len(alt) > 0 {
+			altStr = strings.Join(alt, " ")
+		}
+		return fmt.Sprintf("![%s](%s)", altStr, assetURL(path.Join(
+			"static/bootstrap-icons",
+			fmt.Sprintf("%s.svg", name),
+		)))
 	}
 }
 
 // Asset returns the asset path
 func (d *Doccer) AssetURL(name string) string {
+	name = d.assetName(name)
 
 	if IsLocal(d.config.Server.StaticUrl) {
 		var p = path.Join(d.config.Server.StaticUrl, name)
@@ -407,11 +690,57 @@ func (d *Doccer) AssetURL(name string) string {
 	return d.config.Server.StaticUrl + name + "?raw=true"
 }
 
+// BuildOptions configures the build command
+type BuildOptions struct {
+	Strict bool          // Fail the build on template errors
+	Watch  bool          // Rebuild when the documentation changes
+	Poll   time.Duration // Interval between checks for changes when watching
+}
+
+// Build builds the documentation to the configured output.
+// With the watch option the documentation is rebuilt whenever it changes.
+//
+// Outputs ending in .zip, .tar.gz or .tgz are written as an archive.
 func (d *Doccer) Build() error {
+	if d.buildOptions.Watch {
+		return d.Watch()
+	}
+
+	var _, err = d.build(nil)
+	return err
+}
+
+// build builds the pages to the configured output and returns the number of files written.
+// All pages are built if pages is nil.
+func (d *Doccer) build(pages map[string]bool) (int, error) {
+	var sink, err = output.Open(d.config.Project.OutputDirectory)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := d.buildPages(sink, pages)
+	if cerr := sink.Close(); err == nil {
+		err = cerr
+	}
+	return written, err
+}
+
+// BuildTo builds the documentation to the sink.
+// The sink is not closed after building.
+func (d *Doccer) BuildTo(sink output.Sink) error {
+	var _, err = d.buildPages(sink, nil)
+	return err
+}
+
+// buildPages builds the pages to the sink and returns the number of files written.
+// Pages are selected by the path of their source, all pages and static assets are built if pages is nil.
+func (d *Doccer) buildPages(sink output.Sink, pages map[string]bool) (int, error) {
 	// Build the templates
 	var (
-		err  error
-		last filesystem.Object
+		err       error
+		last      filesystem.Object
+		tplErrors TemplateErrors
+		written   int
 	)
 
 	// Run all build hooks
@@ -419,59 +748,145 @@ func (d *Doccer) Build() error {
 	for _, hook := range h {
 		err = hook(d)
 		if err != nil {
-			return err
+			return written, err
 		}
 	}
 
-	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
-		last = obj
+	// The sprite of a full build only holds the icons of this build,
+	// rebuilding some pages keeps the icons of the other pages
+	if pages == nil {
+		d.resetIcons()
+	}
 
-		var outputDir string
+	// Pages and static assets are kept in memory until every page is rendered,
+	// a build failing on template errors leaves the output as it was
+	var (
+		target  = sink
+		pending = output.NewMemory()
+	)
+	sink = pending
 
-		// Skip directories
-		if obj.IsDirectory() {
-			var dir = obj.(*filesystem.TemplateDirectory)
-			err = os.MkdirAll(dir.Output, 0755)
-			if err != nil {
-				err = fmt.Errorf("error creating directory %s: %s", dir.Output, err)
-				return false
-			}
+	// Static assets are only part of the output if they are served locally.
+	// Icons are only copied when the built files reference them.
+	var icons *iconSink
+	if IsLocal(d.config.Server.StaticUrl) {
+		icons = &iconSink{Sink: sink, icons: make(map[string]bool)}
+		sink = icons
+	}
 
-			outputDir = filepath.Join(dir.Output, "index.html")
-		} else {
-			var t = obj.(*filesystem.Template)
-			outputDir = t.Output
+	if pages == nil && icons != nil {
+		err = d.copyStatic(sink)
+		if err != nil {
+			return written, fmt.Errorf("error copying static files: %s", err)
 		}
+	}
+
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		if err != nil {
+			return false
+		}
+
+		if pages != nil && !pages[sourcePath(obj)] {
+			return true
+		}
+
+		last = obj
 
 		var b bytes.Buffer
 		err = d.renderObject(&b, obj)
+
+		// Template errors are collected, the build fails once all pages are rendered
+		var tplErr *TemplateError
+		if errors.As(err, &tplErr) {
+			tplErrors = append(tplErrors, tplErr)
+			err = nil
+			return true
+		}
+
 		if err != nil {
 			err = fmt.Errorf("error rendering %s: %s", obj.GetName(), err)
 			return false
 		}
 
-		// Write the template to the output directory
-		err = os.WriteFile(outputDir, b.Bytes(), 0644)
+		// Write the template to the output
+		var name = d.outputName(obj)
+		err = sink.WriteFile(name, b.Bytes())
 		if err != nil {
-			err = fmt.Errorf("error writing %s: %s", outputDir, err)
+			err = fmt.Errorf("error writing %s: %s", name, err)
 			return false
 		}
-		return err == nil
+		written++
+		return true
 	})
 	if err != nil {
-		return fmt.Errorf("error building %s: %s", last.GetName(), err)
+		return 0, fmt.Errorf("error building %s: %s", last.GetName(), err)
+	}
+
+	if len(tplErrors) > 0 {
+		return 0, tplErrors
+	}
+
+	if err = pending.CopyTo(target); err != nil {
+		return 0, err
+	}
+
+	sink = target
+	if icons != nil {
+		icons.Sink = target
+		sink = icons
+	}
+
+	// Run all build hooks, after_build_sink hooks can write extra files to the output
+	var sinkHooks = hooks.Get[SinkBuildHook]("after_build_sink")
+	for _, hook := range sinkHooks {
+		err = hook(d, d.config, sink)
+		if err != nil {
+			return written, err
+		}
 	}
 
-	// Run all build hooks
 	var ldHooks = hooks.Get[LoadHook]("after_build")
 	for _, hook := range ldHooks {
 		err = hook(d, d.config)
 		if err != nil {
-			return err
+			return written, err
 		}
 	}
 
-	return nil
+	// The sprite holds the icons used by all rendered pages, including pages added by hooks
+	if d.config.Server.IconSprite && IsLocal(d.config.Server.StaticUrl) {
+		err = d.writeIconSprite(sink)
+		if err != nil {
+			return written, fmt.Errorf("error writing icon sprite: %s", err)
+		}
+	}
+
+	if icons != nil {
+		err = d.writeIcons(icons, pages == nil)
+		if err != nil {
+			return written, fmt.Errorf("error copying icons: %s", err)
+		}
+	}
+
+	return written, nil
+}
+
+// outputName returns the name of the object's output file,
+// relative to the root of the output.
+func (d *Doccer) outputName(obj filesystem.Object) string {
+	var name string
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		name = filepath.Join(o.Output, "index.html")
+	case *filesystem.Template:
+		name = o.Output
+	}
+
+	if rel, err := filepath.Rel(d.config.Project.OutputDirectory, name); err == nil {
+		name = rel
+	}
+
+	return filepath.ToSlash(name)
 }
 
 func (d *Doccer) Init() error {
@@ -540,7 +955,7 @@ func (d *Doccer) Init() error {
 	}
 
 	// Copy the static files
-	err = CopyDirectory(d.embedFS.FS, "assets/static", filepath.Join(DOCCER_DIR, "static"))
+	err = CopyDirectory(d.embedFS.FS, "static", filepath.Join(DOCCER_DIR, "static"))
 	if err != nil {
 		return err
 	}
@@ -548,7 +963,7 @@ func (d *Doccer) Init() error {
 	// Copy the templates
 	return CopyDirectory(
 		d.embedFS.FS,
-		"assets/templates",
+		"templates",
 		filepath.Join(DOCCER_DIR, "templates"),
 	)
 }
@@ -582,14 +997,28 @@ func (d *Doccer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		path  = r.URL.Path
 	)
 
-	if IsLocal(d.config.Server.StaticUrl) && strings.HasPrefix(path, d.config.Server.StaticUrl) {
-		path = filepath.Clean(strings.TrimPrefix(path, d.config.Server.StaticUrl))
-		http.ServeFile(w, r, path)
+	var baseURL, staticURL = d.routes()
+	if d.mounted {
+		// Allow the handler to be mounted with or without stripping the prefix
+		var prefix = strings.TrimSuffix(d.config.Server.BaseURL, "/")
+		if prefix != "" && (path == prefix || strings.HasPrefix(path, prefix+"/")) {
+			path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, prefix), "/")
+		}
+	}
+
+	var isStatic = IsLocal(staticURL) && strings.HasPrefix(path, staticURL)
+	if d.auth != nil && !(isStatic && d.auth.exemptStatic) && !d.auth.authorize(r) {
+		d.auth.challenge(w)
+		return
+	}
+
+	if isStatic {
+		d.serveStatic(w, r, strings.TrimPrefix(path, staticURL))
 		return
 	}
 
 	var (
-		baseUrl       = strings.TrimSuffix(d.config.Server.BaseURL, "/")
+		baseUrl       = strings.TrimSuffix(baseURL, "/")
 		hasBasePrefix = strings.HasPrefix(path, baseUrl)
 	)
 	if !hasBasePrefix && path != "/" {
@@ -599,7 +1028,7 @@ func (d *Doccer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, d.config.Server.BaseURL, http.StatusFound)
 		return
 	}
-	path = strings.TrimPrefix(path, d.config.Server.BaseURL)
+	path = strings.TrimPrefix(path, baseURL)
 	path = strings.TrimPrefix(path, "/")
 	path = strings.TrimSuffix(path, "/")
 
@@ -611,6 +1040,9 @@ func (d *Doccer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// Walk the directory
 	var obj, ok = d.config.RootDirectory.Walk(parts)
+	if !ok && d.serveTagPage(w, parts) {
+		return
+	}
 	if !ok {
 		fmt.Println("Not found", parts)
 		http.NotFound(w, r)
@@ -618,14 +1050,77 @@ func (d *Doccer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var err = d.renderObject(w, obj)
+	if err != nil {
+		d.serveError(w, err)
+	}
+}
+
+// routes returns the base and static URL prefixes requests are matched against.
+// A mounted handler matches paths relative to the base URL.
+func (d *Doccer) routes() (baseURL, staticURL string) {
+	baseURL = d.config.Server.BaseURL
+	staticURL = d.config.Server.StaticUrl
+
+	if d.mounted {
+		var prefix = strings.TrimSuffix(baseURL, "/")
+		if prefix != "" && strings.HasPrefix(staticURL, prefix+"/") {
+			staticURL = strings.TrimPrefix(staticURL, prefix)
+		}
+		baseURL = "/"
+	}
+
+	return baseURL, staticURL
+}
+
+// serveStatic serves a static file from the doccer filesystem
+func (d *Doccer) serveStatic(w http.ResponseWriter, r *http.Request, name string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+
+	if name == CHROMA_CSS {
+		var css, err = d.chromaCSS(true)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		_, _ = w.Write(css)
+		return
+	}
+
+	var f, err = d.embedFS.Open(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	if rs, ok := f.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, info.Name(), info.ModTime(), rs)
+		return
+	}
+
+	b, err := io.ReadAll(f)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
+
+	http.ServeContent(w, r, info.Name(), info.ModTime(), bytes.NewReader(b))
 }
 
 func (d *Doccer) renderObject(w io.Writer, obj filesystem.Object) error {
 	var _, isServing = w.(http.ResponseWriter)
-	var context = d.GetContext(isServing)
+	var context, err = d.GetContext(isServing)
+	if err != nil {
+		return err
+	}
+	context.setPage(obj)
 
 	var h = hooks.Get[func(*Doccer, *Context, filesystem.Object) error]("pre_render_object")
 	for _, hook := range h {
@@ -643,7 +1138,7 @@ func (d *Doccer) renderObject(w io.Writer, obj filesystem.Object) error {
 	if obj.IsDirectory() {
 		var dir = obj.(*filesystem.TemplateDirectory)
 		if dir.Index != nil {
-			addTemplateContext(
+			err = addTemplateContext(
 				context, dir.Index,
 			)
 		} else {
@@ -668,7 +1163,7 @@ func (d *Doccer) renderObject(w io.Writer, obj filesystem.Object) error {
 				//	Object:  v,
 				//	context: context,
 				//}
-				fmt.Fprintf(b, "<p><a href=\"%s\">", ObjectURL(d.config.Server.BaseURL, v, isServing))
+				fmt.Fprintf(b, "<p><a href=\"%s\">", context.relative(ObjectURL(d.config.Server.BaseURL, v, isServing)))
 				fmt.Fprint(b, v.GetTitle())
 				fmt.Fprintf(b, "</a></p>\n")
 				return true
@@ -679,14 +1174,14 @@ func (d *Doccer) renderObject(w io.Writer, obj filesystem.Object) error {
 				//	Object:  v,
 				//	context: context,
 				//}
-				fmt.Fprintf(b, "<p><a href=\"%s\">", ObjectURL(d.config.Server.BaseURL, v, isServing))
+				fmt.Fprintf(b, "<p><a href=\"%s\">", context.relative(ObjectURL(d.config.Server.BaseURL, v, isServing)))
 				fmt.Fprint(b, v.GetTitle())
 				fmt.Fprintf(b, "</a></p>\n")
 				return true
 			})
 
 			tpl.Content = b.String()
-			addTemplateContext(
+			err = addTemplateContext(
 				context, tpl,
 			)
 		}
@@ -694,15 +1189,13 @@ func (d *Doccer) renderObject(w io.Writer, obj filesystem.Object) error {
 	} else {
 		var t = obj.(*filesystem.Template)
 
-		addTemplateContext(
+		err = addTemplateContext(
 			context, t,
 		)
 	}
-
-	var err = d.config.Tpl.ExecuteTemplate(w, "base", context)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return d.executeTemplate(w, "base", context, d.contextFuncs(context))
 }
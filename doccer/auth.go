@@ -0,0 +1,155 @@
+package doccer
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// authenticator protects the documentation server
+// with HTTP basic auth and / or bearer tokens.
+type authenticator struct {
+	realm        string
+	users        map[string][]byte
+	tokens       [][]byte
+	exemptStatic bool
+
+	// Cache of credentials which have already been verified.
+	// bcrypt is slow by design, we do not want to pay for it on every request.
+	mu       sync.RWMutex
+	verified map[[sha256.Size]byte]struct{}
+}
+
+// newAuthenticator creates a new authenticator from the auth configuration
+func newAuthenticator(c *AuthConfig) (*authenticator, error) {
+	var a = &authenticator{
+		realm:        c.Realm,
+		users:        make(map[string][]byte),
+		tokens:       make([][]byte, 0, len(c.Tokens)),
+		exemptStatic: c.ExemptStatic,
+		verified:     make(map[[sha256.Size]byte]struct{}),
+	}
+
+	if a.realm == "" {
+		a.realm = "Documentation"
+	}
+
+	for _, token := range c.Tokens {
+		if token == "" {
+			continue
+		}
+		a.tokens = append(a.tokens, []byte(token))
+	}
+
+	if c.Htpasswd != "" {
+		var err = a.loadHtpasswd(c.Htpasswd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(a.users) == 0 && len(a.tokens) == 0 {
+		return nil, fmt.Errorf("'auth' requires 'htpasswd' or 'tokens' to be set")
+	}
+
+	return a, nil
+}
+
+// loadHtpasswd loads users from a htpasswd file.
+// Only bcrypt hashed passwords are supported.
+func (a *authenticator) loadHtpasswd(path string) error {
+	var b, err = os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading htpasswd file: %s", err)
+	}
+
+	var scanner = bufio.NewScanner(bytes.NewReader(b))
+	var lineNo = 0
+	for scanner.Scan() {
+		lineNo++
+
+		var line = strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var user, hash, ok = strings.Cut(line, ":")
+		if !ok || user == "" {
+			return fmt.Errorf("%s:%d: invalid htpasswd entry", path, lineNo)
+		}
+
+		if !strings.HasPrefix(hash, "$2a$") &&
+			!strings.HasPrefix(hash, "$2b$") &&
+			!strings.HasPrefix(hash, "$2y$") {
+			return fmt.Errorf("%s:%d: unsupported hash for user %q, only bcrypt is supported", path, lineNo, user)
+		}
+
+		a.users[user] = []byte(hash)
+	}
+
+	return scanner.Err()
+}
+
+// authorize returns true if the request carries valid credentials
+func (a *authenticator) authorize(r *http.Request) bool {
+	var header = r.Header.Get("Authorization")
+	if header == "" {
+		return false
+	}
+
+	if token, ok := strings.CutPrefix(header, "Bearer "); ok {
+		var t = []byte(strings.TrimSpace(token))
+		for _, allowed := range a.tokens {
+			if subtle.ConstantTimeCompare(t, allowed) == 1 {
+				return true
+			}
+		}
+		return false
+	}
+
+	var user, pass, ok = r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	var hash, exists = a.users[user]
+	if !exists {
+		return false
+	}
+
+	var key = sha256.Sum256([]byte(user + ":" + pass))
+	a.mu.RLock()
+	_, ok = a.verified[key]
+	a.mu.RUnlock()
+	if ok {
+		return true
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(pass)) != nil {
+		return false
+	}
+
+	a.mu.Lock()
+	a.verified[key] = struct{}{}
+	a.mu.Unlock()
+	return true
+}
+
+// challenge asks the client to authenticate
+func (a *authenticator) challenge(w http.ResponseWriter) {
+	if len(a.users) > 0 {
+		w.Header().Add("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", a.realm))
+	}
+	if len(a.tokens) > 0 {
+		w.Header().Add("WWW-Authenticate", fmt.Sprintf("Bearer realm=%q", a.realm))
+	}
+	http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+}
@@ -0,0 +1,394 @@
+package doccer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/render"
+)
+
+// TestOptions configures the test command
+type TestOptions struct {
+	Timeout time.Duration // Maximum time to build and run a single code block
+}
+
+// CodeTestFailure is a code block which failed to build, run or print its expected output
+type CodeTestFailure struct {
+	File    string // Source file containing the code block
+	Line    int    // Line of the problem in the source file
+	Message string // Output of the go command or the difference in output
+}
+
+func (f *CodeTestFailure) Error() string {
+	return fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+}
+
+var (
+	packageClauseRegex = regexp.MustCompile(`(?m)^package\s+(\w+)`)
+	funcMainRegex      = regexp.MustCompile(`(?m)^func\s+main\s*\(\s*\)`)
+	topLevelDeclRegex  = regexp.MustCompile(`(?m)^(func|type)\b`)
+	goPositionRegex    = regexp.MustCompile(`(?m)^(?:vet: )?(?:\./)?main\.go:(\d+)(?::\d+)?:`)
+)
+
+func init() {
+	hooks.Register("parse_args", 0, func(d *Doccer, fs *flag.FlagSet) ParseFlagFn {
+		var timeout = fs.Duration("timeout", time.Minute, "maximum time to build and run a single code block when testing")
+		return func(d *Doccer, fs *flag.FlagSet) error {
+			d.testOptions.Timeout = *timeout
+			return nil
+		}
+	})
+}
+
+// codeTest is a go code block of a page to test
+type codeTest struct {
+	file   string   // Source file of the page
+	line   int      // Line of the first line of code in the source file
+	source string   // Source of the package, the code block with any wrapping added
+	lines  []int    // Line in the code block of every line of the package, 0 for added lines
+	isMain bool     // The package can be run
+	env    []string // Environment of the go command, set before running
+}
+
+// write adds the text to the source of the package.
+// from is the line of the text in the code block, 0 if the text was added.
+func (t *codeTest) write(text string, from int) {
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		t.source += line + "\n"
+		if from > 0 {
+			t.lines = append(t.lines, from+i)
+		} else {
+			t.lines = append(t.lines, 0)
+		}
+	}
+}
+
+// Test builds and runs the go code blocks of all markdown pages.
+//
+// Blocks with a package clause are tested as they are, other blocks are only
+// tested if marked with ```go test and are wrapped in a main package.
+// Blocks marked with ```go notest are skipped.
+// If the block contains an // Output: comment the output of the program must match it.
+func (d *Doccer) Test() error {
+	var tests = make([]*codeTest, 0)
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		var t *filesystem.Template
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			t = o.Index
+		case *filesystem.Template:
+			t = o
+		}
+
+		if t != nil && t.IsTextFile() {
+			tests = append(tests, codeTests(t)...)
+		}
+		return true
+	})
+
+	if len(tests) == 0 {
+		fmt.Println("No go code blocks to test")
+		return nil
+	}
+
+	var dir, env, cleanup, err = d.codeTestDir()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	var timeout = d.testOptions.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+
+	var failures = 0
+	for i, test := range tests {
+		var pkg = filepath.Join(dir, fmt.Sprintf("block%d", i+1))
+		test.env = env
+		if err := test.run(pkg, timeout); err != nil {
+			fmt.Println(err)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d code blocks failed", failures, len(tests))
+	}
+
+	fmt.Printf("Tested %d code block(s)\n", len(tests))
+	return nil
+}
+
+// codeTests returns the go code blocks of the template which should be tested
+func codeTests(t *filesystem.Template) []*codeTest {
+	var tests = make([]*codeTest, 0)
+	for _, block := range render.CodeBlocks(t.Name, []byte(t.Source())) {
+		if (block.Language != "go" && block.Language != "golang") || block.HasAttribute("notest") {
+			continue
+		}
+
+		var test = &codeTest{
+			file: sourceFile(t),
+			line: t.ContentLine + block.Line - 1,
+		}
+
+		var pkg = packageClauseRegex.FindStringSubmatch(block.Code)
+		switch {
+		case pkg != nil:
+			test.write(block.Code, 1)
+			test.isMain = pkg[1] == "main" && funcMainRegex.MatchString(block.Code)
+		case !block.HasAttribute("test"):
+			// Fragments are only tested when marked
+			continue
+		case topLevelDeclRegex.MatchString(block.Code):
+			test.write("package main", 0)
+			test.write(block.Code, 1)
+			if !funcMainRegex.MatchString(block.Code) {
+				test.write("func main() {}", 0)
+			}
+			test.isMain = true
+		default:
+			// Statements are wrapped in a main function, leading imports are kept outside of it
+			var imports, statements = splitImports(block.Code)
+			test.write("package main", 0)
+			if imports != "" {
+				test.write(imports, 1)
+			}
+			test.write("func main() {", 0)
+			test.write(statements, strings.Count(imports, "\n")+1)
+			test.write("}", 0)
+			test.isMain = true
+		}
+
+		tests = append(tests, test)
+	}
+	return tests
+}
+
+// run writes the package to the directory, vets it and runs it if it is a main package
+func (t *codeTest) run(dir string, timeout time.Duration) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(t.source), 0644); err != nil {
+		return err
+	}
+
+	if _, err := t.goCommand(dir, timeout, "vet", "."); err != nil {
+		return err
+	}
+
+	if !t.isMain {
+		return nil
+	}
+
+	var out, err = t.goCommand(dir, timeout, "run", ".")
+	if err != nil {
+		return err
+	}
+
+	var expected, ok = expectedOutput(t.source)
+	if !ok {
+		return nil
+	}
+
+	var got = strings.TrimSpace(strings.ReplaceAll(out, "\r\n", "\n"))
+	if got != expected {
+		return &CodeTestFailure{
+			File:    t.file,
+			Line:    t.line,
+			Message: fmt.Sprintf("output does not match\ngot:\n%s\nwant:\n%s", got, expected),
+		}
+	}
+
+	return nil
+}
+
+// goCommand runs the go command in the directory.
+// Positions in the output of a failed command are rewritten to the lines in the source file.
+func (t *codeTest) goCommand(dir string, timeout time.Duration, args ...string) (string, error) {
+	var ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	var cmd = exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.Env = t.env
+
+	var err = cmd.Run()
+	if err == nil {
+		return stdout.String(), nil
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "", &CodeTestFailure{
+			File:    t.file,
+			Line:    t.line,
+			Message: fmt.Sprintf("go %s timed out after %s", args[0], timeout),
+		}
+	}
+
+	// Package headers printed by the go command only name the generated package
+	var lines = make([]string, 0)
+	for _, text := range strings.Split(strings.TrimSpace(stderr.String()), "\n") {
+		if !strings.HasPrefix(text, "# ") {
+			lines = append(lines, text)
+		}
+	}
+
+	var (
+		line    = t.line
+		message = strings.Join(lines, "\n")
+	)
+
+	if match := goPositionRegex.FindStringSubmatch(message); match != nil {
+		line = t.sourceLine(match[1])
+	}
+
+	message = goPositionRegex.ReplaceAllStringFunc(message, func(s string) string {
+		var match = goPositionRegex.FindStringSubmatch(s)
+		return fmt.Sprintf("%s:%d:", t.file, t.sourceLine(match[1]))
+	})
+
+	return "", &CodeTestFailure{
+		File:    t.file,
+		Line:    line,
+		Message: fmt.Sprintf("go %s failed: %s\n%s", args[0], err, message),
+	}
+}
+
+// sourceLine returns the line in the source file for a line of the generated package
+func (t *codeTest) sourceLine(s string) int {
+	var line, _ = strconv.Atoi(s)
+	if line < 1 || line > len(t.lines) || t.lines[line-1] == 0 {
+		return t.line
+	}
+	return t.line + t.lines[line-1] - 1
+}
+
+// splitImports splits the import declarations at the start of the code from the statements after them
+func splitImports(code string) (imports, statements string) {
+	var (
+		lines = strings.SplitAfter(code, "\n")
+		group = false
+		end   = 0
+	)
+
+	for i, line := range lines {
+		var trimmed = strings.TrimSpace(line)
+		switch {
+		case group:
+			group = trimmed != ")"
+		case strings.HasPrefix(trimmed, "import ("):
+			group = true
+		case strings.HasPrefix(trimmed, "import ") || trimmed == "":
+		default:
+			return strings.Join(lines[:end], ""), strings.Join(lines[end:], "")
+		}
+		end = i + 1
+	}
+
+	return strings.Join(lines[:end], ""), ""
+}
+
+// expectedOutput returns the output expected by the // Output: comment of the code
+func expectedOutput(code string) (string, bool) {
+	var lines = strings.Split(code, "\n")
+	for i, line := range lines {
+		var rest, ok = strings.CutPrefix(strings.TrimSpace(line), "// Output:")
+		if !ok {
+			continue
+		}
+
+		var expected = []string{strings.TrimSpace(rest)}
+		for _, next := range lines[i+1:] {
+			var comment, ok = strings.CutPrefix(strings.TrimSpace(next), "//")
+			if !ok {
+				break
+			}
+			expected = append(expected, strings.TrimPrefix(comment, " "))
+		}
+
+		return strings.TrimSpace(strings.Join(expected, "\n")), true
+	}
+	return "", false
+}
+
+// codeTestDir creates the directory the code blocks are tested in,
+// it returns the environment to run the go command with.
+//
+// The directory is a module outside of the project, so an interrupted test leaves nothing behind in it.
+// If the input directory is part of a go module, the module is added with a go.work file,
+// allowing code blocks to import the packages of the module.
+func (d *Doccer) codeTestDir() (string, []string, func(), error) {
+	var dir, err = os.MkdirTemp("", "doccer_test")
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	var (
+		cleanup  = func() { os.RemoveAll(dir) }
+		env      = os.Environ()
+		commands = [][]string{{"go", "mod", "init", "doccertest"}}
+	)
+
+	if root, ok := d.goModuleRoot(); ok {
+		commands = append(commands, []string{"go", "work", "init", ".", root})
+
+		// The workspace is found from the test directory, the module
+		// requirements cannot be changed in workspace mode
+		env = append(env, "GOWORK=", "GOFLAGS="+strings.TrimSpace(os.Getenv("GOFLAGS")+" -mod=readonly"))
+	}
+
+	for _, args := range commands {
+		var cmd = exec.Command(args[0], args[1:]...)
+		cmd.Dir = dir
+		cmd.Env = env
+		if out, err := cmd.CombinedOutput(); err != nil {
+			cleanup()
+			return "", nil, nil, fmt.Errorf("error creating test module: %s\n%s", err, out)
+		}
+	}
+
+	return dir, env, cleanup, nil
+}
+
+// goModuleRoot returns the directory of the go module containing the input directory
+func (d *Doccer) goModuleRoot() (string, bool) {
+	if d.docsFS != nil || strings.HasSuffix(strings.ToLower(d.config.Project.InputDirectory), ".zip") {
+		return "", false
+	}
+
+	var dir, err = filepath.Abs(d.config.Project.InputDirectory)
+	if err != nil {
+		return "", false
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, true
+		}
+
+		var parent = filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
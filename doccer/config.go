@@ -1,42 +1,88 @@
 package doccer
 
 import (
+	"fmt"
 	"html/template"
-	"os"
+	"io/fs"
+	"slices"
+	"strings"
 
 	"github.com/Nigel2392/doccer/doccer/filesystem"
 	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/render"
+	"github.com/alecthomas/chroma/v2/styles"
+	"gopkg.in/yaml.v3"
 )
 
 type (
 	ServerConfig struct {
-		Hostname    string `yaml:"hostname"`    // Hostname to use for the server
-		Port        int    `yaml:"port"`        // Port to use for the server
-		BaseURL     string `yaml:"base_url"`    // Base URL for the server
-		StaticUrl   string `yaml:"static_url"`  // Static URL for assets
-		StaticRoot  string `yaml:"static_root"` // Static root directory for assets
-		PrivateKey  string `yaml:"private_key"` // Private key for the server
-		Certificate string `yaml:"certificate"` // Certificate for the server
+		Hostname     string      `yaml:"hostname"`      // Hostname to use for the server
+		Port         int         `yaml:"port"`          // Port to use for the server
+		BaseURL      string      `yaml:"base_url"`      // Base URL for the server
+		StaticUrl    string      `yaml:"static_url"`    // Static URL for assets
+		StaticRoot   string      `yaml:"static_root"`   // Static root directory for assets
+		PrivateKey   string      `yaml:"private_key"`   // Private key for the server
+		Certificate  string      `yaml:"certificate"`   // Certificate for the server
+		Auth         *AuthConfig `yaml:"auth"`          // Authentication for the server
+		RelativeURLs bool        `yaml:"relative_urls"` // Build links relative to the current page
+
+		FingerprintAssets bool `yaml:"fingerprint_assets"` // Add a content hash to the names of static assets
+		IconSprite        bool `yaml:"icon_sprite"`        // Reference icons from a sprite when building
+	}
+
+	AuthConfig struct {
+		Realm        string   `yaml:"realm"`         // Realm reported to the client
+		Htpasswd     string   `yaml:"htpasswd"`      // Path to a htpasswd file with bcrypt hashed passwords
+		Tokens       []string `yaml:"tokens"`        // Bearer tokens which are allowed access
+		ExemptStatic bool     `yaml:"exempt_static"` // Allow unauthenticated access to static assets
 	}
 
 	ProjectConfig struct {
-		Name            string `yaml:"name"`       // Project name
-		Version         string `yaml:"version"`    // Project version
-		Repository      string `yaml:"repository"` // Repository URL
-		InputDirectory  string `yaml:"input"`      // Documentation root directory
-		OutputDirectory string `yaml:"output"`     // Output directory
+		Name              string   `yaml:"name"`                // Project name
+		Version           string   `yaml:"version"`             // Project version
+		Repository        string   `yaml:"repository"`          // Repository URL
+		RepositoryEditURL string   `yaml:"repository_edit_url"` // Forge name (github, gitlab, gitea) or edit URL pattern
+		RepositoryBranch  string   `yaml:"repository_branch"`   // Branch the documentation is edited on, defaults to main
+		InputDirectory    string   `yaml:"input"`               // Documentation root directory or .zip archive
+		Overlays          []string `yaml:"overlays"`            // Extra documentation roots, layered below the input
+		OutputDirectory   string   `yaml:"output"`              // Output directory
+	}
+
+	FooterConfig struct {
+		Items          []MenuItem `yaml:"items"`            // Links shown in the footer of the navigation
+		Text           string     `yaml:"text"`             // Text shown at the bottom of every page
+		Copyright      string     `yaml:"copyright"`        // Copyright line shown at the bottom of every page
+		HideDoccerLink bool       `yaml:"hide_doccer_link"` // Hide the link to the Doccer repository
+	}
+
+	HighlightConfig struct {
+		Style       string `yaml:"style"`        // Chroma style for code blocks, defaults to github
+		DarkStyle   string `yaml:"dark_style"`   // Chroma style used with a dark color scheme, defaults to monokai
+		LineNumbers bool   `yaml:"line_numbers"` // Show line numbers in code blocks
+	}
+
+	LintConfig struct {
+		Format string            `yaml:"format"` // Output format of the lint command, text or json
+		Rules  map[string]string `yaml:"rules"`  // Severity per rule: error, warning or off
 	}
 
 	Config struct {
-		Server   ServerConfig           `yaml:"server"`   // Server configuration
-		Project  ProjectConfig          `yaml:"project"`  // Project configuration
-		Context  map[string]interface{} `yaml:"context"`  // Extra context for generating documentation
-		Features []string               `yaml:"features"` // Features to enable
-		Menu     *Menu                  `yaml:"menu"`     // Menu items
+		Server      ServerConfig           `yaml:"server"`       // Server configuration
+		Project     ProjectConfig          `yaml:"project"`      // Project configuration
+		Context     map[string]interface{} `yaml:"context"`      // Extra context for generating documentation
+		Features    []string               `yaml:"features"`     // Features to enable
+		Menu        *Menu                  `yaml:"menu"`         // Menu items
+		Footer      *FooterConfig          `yaml:"footer"`       // Footer links and text
+		HeaderLinks []MenuItem             `yaml:"header_links"` // Links shown above the content, defaults to the repository
+		Lint        *LintConfig            `yaml:"lint"`         // Lint rules and output
+		Highlight   *HighlightConfig       `yaml:"highlight"`    // Syntax highlighting of code blocks
 
 		Instance      *Doccer                       `yaml:"-"` // Doccer instance
 		Tpl           *template.Template            `yaml:"-"` // HTML Template
 		RootDirectory *filesystem.TemplateDirectory `yaml:"-"` // Root directory
+
+		// Parsed config file, used to report the lines of invalid keys
+		node *yaml.Node
 	}
 )
 
@@ -48,18 +94,112 @@ func NewConfig(instance *Doccer) *Config {
 	}
 }
 
-// UnmarshalYAML unmarshals the config from YAML
+// clone returns a copy of the config which can be changed without changing c
+func (c *Config) clone() *Config {
+	var config = *c
+	if c.Menu != nil {
+		var menu = *c.Menu
+		config.Menu = &menu
+	}
+	if c.Footer != nil {
+		var footer = *c.Footer
+		config.Footer = &footer
+	}
+	if c.Lint != nil {
+		var lint = *c.Lint
+		config.Lint = &lint
+	}
+	if c.Highlight != nil {
+		var highlight = *c.Highlight
+		config.Highlight = &highlight
+	}
+	if c.Server.Auth != nil {
+		var auth = *c.Server.Auth
+		config.Server.Auth = &auth
+	}
+	return &config
+}
+
+// Init validates the config and loads the documentation tree.
+//
+// All problems with the configuration are returned at once as ValidationErrors.
 func (c *Config) Init() error {
+	var v = &validator{node: c.node}
+	if c.node != nil {
+		v.file = c.Instance.configPath
+	}
+
 	if c.Project.Name == "" {
-		raise("'project' is required")
+		v.addf("project.name", "'name' is required")
 	}
 
 	if c.Project.Version == "" {
-		raise("'version' is required")
+		v.addf("project.version", "'version' is required")
+	}
+
+	if c.Project.InputDirectory == "" && c.Instance.docsFS == nil {
+		v.addf("project.input", "'input' is required")
+	}
+
+	if forge, ok := c.Project.forge(); ok {
+		if !strings.Contains(forge.Edit, "{path}") {
+			v.addf("project.repository_edit_url", "unknown forge %q, use github, gitlab, gitea or a pattern containing {path}", c.Project.RepositoryEditURL)
+		} else if strings.Contains(forge.Edit, "{repository}") && c.Project.Repository == "" {
+			v.addf("project.repository", "'repository' is required when using repository_edit_url")
+		}
+	}
+
+	if c.Highlight == nil {
+		c.Highlight = &HighlightConfig{}
+	}
+
+	if c.Highlight.Style == "" {
+		c.Highlight.Style = DEFAULT_HIGHLIGHT_STYLE
+	}
+
+	if c.Highlight.DarkStyle == "" {
+		c.Highlight.DarkStyle = DEFAULT_HIGHLIGHT_DARK_STYLE
+	}
+
+	if _, ok := styles.Registry[strings.ToLower(c.Highlight.Style)]; !ok {
+		v.addf("highlight.style", "unknown highlight style %q", c.Highlight.Style)
+	}
+
+	if _, ok := styles.Registry[strings.ToLower(c.Highlight.DarkStyle)]; !ok {
+		v.addf("highlight.dark_style", "unknown highlight style %q", c.Highlight.DarkStyle)
+	}
+
+	render.Configure(render.Options{
+		LineNumbers: c.Highlight.LineNumbers,
+	})
+
+	if c.Lint == nil {
+		c.Lint = &LintConfig{}
+	}
+
+	switch strings.ToLower(c.Lint.Format) {
+	case "", "text", "json":
+	default:
+		v.addf("lint.format", "unknown lint format %q, use text or json", c.Lint.Format)
+	}
+
+	var rules = make([]string, 0, len(c.Lint.Rules))
+	for rule := range c.Lint.Rules {
+		rules = append(rules, rule)
 	}
+	slices.Sort(rules)
 
-	if c.Project.InputDirectory == "" {
-		raise("'input' is required")
+	for _, rule := range rules {
+		var severity = c.Lint.Rules[rule]
+		if _, ok := LintRules[rule]; !ok {
+			v.addf("lint.rules."+rule, "unknown lint rule %q", rule)
+			continue
+		}
+		switch strings.ToLower(severity) {
+		case LintError, LintWarning, LintOff:
+		default:
+			v.addf("lint.rules."+rule, "unknown severity %q for %s, use error, warning or off", severity, rule)
+		}
 	}
 
 	if c.Server.Port == 0 {
@@ -82,15 +222,53 @@ func (c *Config) Init() error {
 		c.Context = make(map[string]interface{})
 	}
 
-	// Create the output directory
-	var err = os.MkdirAll(c.Project.OutputDirectory, 0755)
-	if err != nil {
-		return err
+	if c.Menu == nil {
+		c.Menu = &Menu{}
+	}
+
+	if c.Footer == nil {
+		c.Footer = &FooterConfig{}
+	}
+
+	if c.HeaderLinks == nil && c.Project.Repository != "" {
+		c.HeaderLinks = []MenuItem{
+			{Name: "View on Github", URL: c.Project.Repository, Icon: "github"},
+		}
 	}
 
-	// Check if the root directory exists
-	if _, err := os.Stat(c.Project.InputDirectory); os.IsNotExist(err) {
-		raise("input root does not exist")
+	var (
+		docs   = c.Instance.docsFS
+		layers = make([]fs.FS, 0, len(c.Project.Overlays)+1)
+		roots  = make([]string, 0, len(c.Project.Overlays)+1)
+	)
+	if docs == nil && c.Project.InputDirectory != "" {
+		for i, root := range append([]string{c.Project.InputDirectory}, c.Project.Overlays...) {
+			var key = "project.input"
+			if i > 0 {
+				key = fmt.Sprintf("project.overlays.%d", i-1)
+			}
+
+			var layer, err = filesystem.OpenFS(root)
+			if err != nil {
+				v.addf(key, "error opening input %s: %s", root, err)
+				continue
+			}
+			layers = append(layers, layer)
+			roots = append(roots, root)
+		}
+
+		if len(layers) > 0 {
+			docs = layers[0]
+		}
+		if len(layers) > 1 {
+			docs = filesystem.Overlay(layers...)
+		}
+	}
+
+	// The documentation tree can only be loaded from a valid input,
+	// other problems are reported together with the problems of the menus
+	if docs == nil {
+		return v.err()
 	}
 
 	var files = []string{
@@ -99,6 +277,8 @@ func (c *Config) Init() error {
 		"templates/main.tmpl",
 		"templates/head.tmpl",
 		"templates/base.tmpl",
+		"templates/print.tmpl",
+		"templates/error.tmpl",
 	}
 
 	// Create the template
@@ -106,27 +286,44 @@ func (c *Config) Init() error {
 
 	tpl.Funcs(c.Instance.TemplateFuncs())
 
-	tpl, err = tpl.ParseFS(c.Instance.embedFS, files...)
+	// Problems in the templates are reported together with the problems of the documentation
+	tpl, err := tpl.ParseFS(c.Instance.embedFS, files...)
 	if err != nil {
-		return err
+		v.addf("templates", "error parsing templates: %s", err)
 	}
 
 	// Create the root directory
-	var (
-		inp = c.Project.InputDirectory
-		out = c.Project.OutputDirectory
+	rootDirectory, err := filesystem.NewRootDirectory(
+		docs, c.Project.Name, c.Project.InputDirectory, c.Project.OutputDirectory,
 	)
-	rootDirectory, err := filesystem.NewTemplateDirectory(nil, "", inp, inp, out, "", 0)
 	if err != nil {
-		return err
+		v.addf("project.input", "error loading documentation: %s", err)
+		return v.err()
+	}
+
+	// Files from overlays are located in the layer they are read from
+	if len(layers) > 1 {
+		setLayerRoots(rootDirectory, layers, roots)
 	}
 
 	c.Tpl = tpl
-	rootDirectory.Name = c.Project.Name
-	rootDirectory.Root = inp
-	rootDirectory.Output = out
 	c.RootDirectory = rootDirectory
 
+	if !c.Menu.Auto {
+		v.validateMenu(c.Menu.Items, rootDirectory, "menu.items", 0)
+	}
+	v.validateMenu(c.Footer.Items, rootDirectory, "footer.items", MAX_MENU_ITEMS_DEPTH)
+	v.validateMenu(c.HeaderLinks, rootDirectory, "header_links", MAX_MENU_ITEMS_DEPTH)
+
+	// Tag pages are generated in the tags directory of the output
+	if err := c.Instance.checkTagPages(); err != nil {
+		v.addf("project.input", "%s", err)
+	}
+
+	if err := v.err(); err != nil {
+		return err
+	}
+
 	var loadedHooks = hooks.Get[LoadHook]("app_loaded")
 	for _, hook := range loadedHooks {
 		if err := hook(c.Instance, c); err != nil {
@@ -136,3 +333,32 @@ func (c *Config) Init() error {
 
 	return nil
 }
+
+// setLayerRoots sets the root of every page to the root of the first layer containing it
+func setLayerRoots(root *filesystem.TemplateDirectory, layers []fs.FS, roots []string) {
+	var set = func(t *filesystem.Template) {
+		for i, layer := range layers {
+			if _, err := fs.Stat(layer, t.Path); err == nil {
+				t.Root = roots[i]
+				return
+			}
+		}
+	}
+
+	var setAll = func(obj filesystem.Object) bool {
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			if o.Index != nil {
+				set(o.Index)
+			}
+		case *filesystem.Template:
+			set(o)
+		}
+		return true
+	}
+
+	root.ForEach(setAll)
+	if root.Partials != nil {
+		root.Partials.ForEach(setAll)
+	}
+}
@@ -3,6 +3,8 @@ package doccer
 import (
 	"encoding/json"
 	"html/template"
+	"path"
+	"strings"
 
 	"github.com/Nigel2392/doccer/doccer/filesystem"
 )
@@ -15,6 +17,15 @@ type MenuItem struct {
 	Icon       string            `yaml:"icon"`
 	Attributes map[string]string `yaml:"attributes"`
 	Items      []MenuItem        `yaml:"items"`
+
+	// Set if the item points to the page being rendered
+	Active bool `yaml:"-"`
+
+	// Set if the item contains the page being rendered
+	Expanded bool `yaml:"-"`
+
+	// The object the item points to, nil for external links
+	object filesystem.Object
 }
 
 func (m MenuItem) PreviewLetter() string {
@@ -24,6 +35,11 @@ func (m MenuItem) PreviewLetter() string {
 	return "?"
 }
 
+// IsExternal returns true if the item links outside of the documentation
+func (m MenuItem) IsExternal() bool {
+	return !IsLocal(m.URL)
+}
+
 func (m MenuItem) Copy() MenuItem {
 	var items = make([]MenuItem, len(m.Items))
 	for i, item := range m.Items {
@@ -36,15 +52,39 @@ func (m MenuItem) Copy() MenuItem {
 		Icon:       m.Icon,
 		Attributes: m.Attributes,
 		Items:      items,
+		Active:     m.Active,
+		Expanded:   m.Expanded,
+		object:     m.object,
 	}
 }
 
 // Menu represents a menu
 type Menu struct {
 	Logo  string     `yaml:"logo"`
+	Auto  bool       `yaml:"auto"` // Generate the items from the whole directory tree
 	Items []MenuItem `yaml:"items"`
 }
 
+// activeItems returns a copy of the items with the item pointing to obj marked active.
+// Items containing the active item are expanded.
+func activeItems(items []MenuItem, obj filesystem.Object) ([]MenuItem, bool) {
+	var (
+		active = make([]MenuItem, len(items))
+		found  bool
+	)
+	for i, item := range items {
+		active[i] = item.Copy()
+		active[i].Active = item.object != nil && item.object == obj
+
+		var contains bool
+		active[i].Items, contains = activeItems(item.Items, obj)
+		active[i].Expanded = contains || (active[i].Active && len(item.Items) > 0)
+
+		found = found || contains || active[i].Active
+	}
+	return active, found
+}
+
 func makeContextObject(object filesystem.Object, context *Context) filesystem.Object {
 	if object == nil {
 		return nil
@@ -95,7 +135,9 @@ func (c *contextObject) IsDirectory() bool {
 }
 
 func (c *contextObject) URL() string {
-	return ObjectURL(c.context.Config.Server.BaseURL, c.Object, c.context.isServing)
+	return c.context.relative(
+		ObjectURL(c.context.Config.Server.BaseURL, c.Object, c.context.isServing),
+	)
 }
 
 func (c *contextObject) ServeURL() string {
@@ -136,6 +178,12 @@ type Context struct {
 	// Current object being rendered
 	object filesystem.Object
 
+	// URL of the page being rendered when building with relative URLs
+	pageURL string
+
+	// Flag to indicate the page is rendered for an export, which has no static files
+	isExporting bool
+
 	// The current configuration
 	Config *Config
 
@@ -151,6 +199,12 @@ type Context struct {
 	// Footer
 	Footer *Menu
 
+	// Links shown above the content
+	HeaderLinks []MenuItem
+
+	// History of the page being rendered, set by the git_history feature
+	History *PageHistory
+
 	// The directory tree
 	Tree map[string]interface{}
 }
@@ -160,6 +214,81 @@ func (c *Context) IsServing() bool {
 	return c.isServing
 }
 
+// RootURL returns the URL of the documentation root
+func (c *Context) RootURL() string {
+	return c.relative(c.Config.Server.BaseURL)
+}
+
+// AssetURL returns the URL of a static asset
+func (c *Context) AssetURL(name string) string {
+	var d = c.Config.Instance
+	if c.pageURL == "" || !IsLocal(c.Config.Server.StaticUrl) {
+		return d.AssetURL(name)
+	}
+
+	// Assets are copied into the output when building
+	return c.relative(path.Join("/", c.Config.Server.BaseURL, d.staticOutputDir(), d.assetName(name)))
+}
+
+// setPage sets the page being rendered.
+// The menu item of the page is marked active, when building
+// with relative URLs the menus are rewritten relative to the page.
+func (c *Context) setPage(obj filesystem.Object) {
+	if c.Menu != nil {
+		var items, _ = activeItems(c.Menu.Items, obj)
+		c.Menu = &Menu{
+			Logo:  c.Menu.Logo,
+			Auto:  c.Menu.Auto,
+			Items: items,
+		}
+	}
+
+	if c.isServing || !c.Config.Server.RelativeURLs {
+		return
+	}
+
+	c.pageURL = ObjectURL(c.Config.Server.BaseURL, obj, false)
+	if obj.IsDirectory() {
+		c.pageURL += "index.html"
+	}
+
+	if c.Menu != nil {
+		c.Menu = &Menu{
+			Logo:  c.Menu.Logo,
+			Auto:  c.Menu.Auto,
+			Items: c.relativeItems(c.Menu.Items),
+		}
+	}
+
+	if c.Footer != nil {
+		c.Footer = &Menu{
+			Logo:  c.Footer.Logo,
+			Items: c.relativeItems(c.Footer.Items),
+		}
+	}
+
+	c.HeaderLinks = c.relativeItems(c.HeaderLinks)
+}
+
+// relative returns the URL relative to the page being rendered.
+// URLs are returned as is when not building with relative URLs.
+func (c *Context) relative(url string) string {
+	if c.pageURL == "" || !strings.HasPrefix(url, "/") || strings.HasPrefix(url, "//") {
+		return url
+	}
+	return relativeURL(c.pageURL, url)
+}
+
+func (c *Context) relativeItems(items []MenuItem) []MenuItem {
+	var relative = make([]MenuItem, len(items))
+	for i, item := range items {
+		relative[i] = item.Copy()
+		relative[i].URL = c.relative(item.URL)
+		relative[i].Items = c.relativeItems(item.Items)
+	}
+	return relative
+}
+
 // Object represents the documentation object
 func (c *Context) Object() filesystem.Object {
 	return makeContextObject(c.object, c)
@@ -0,0 +1,322 @@
+package doccer
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"html"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+)
+
+var (
+	imgSrcRegex   = regexp.MustCompile(`(<img\s[^>]*?src=")([^"]*)(")`)
+	voidTagRegex  = regexp.MustCompile(`<(area|base|br|col|embed|hr|img|input|link|meta|source|track|wbr)(\s[^>]*?)?\s*/?>`)
+	epubHrefRegex = regexp.MustCompile(`\shref="#([^"]*)"`)
+)
+
+const epubContainer = `<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+    <rootfiles>
+        <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+    </rootfiles>
+</container>
+`
+
+const epubStyle = `body { font-family: sans-serif; line-height: 1.5; }
+img { max-width: 100%; height: auto; }
+pre { white-space: pre-wrap; font-size: 0.85em; padding: 0.5em; background-color: #f4f4f4; }
+code { font-size: 0.9em; }
+nav ol { list-style-type: none; }
+`
+
+// epubChapter is a single XHTML document inside of an EPUB
+type epubChapter struct {
+	page *ExportPage
+	id   string
+	file string
+}
+
+// epubImage is an image referenced from one of the pages
+type epubImage struct {
+	id        string
+	file      string
+	mediaType string
+	data      []byte
+}
+
+// ExportEPUB writes the documentation to an EPUB 3 file.
+//
+// Chapters follow the order of the documentation tree and the
+// navigation document mirrors the directory hierarchy.
+func (d *Doccer) ExportEPUB(file string) error {
+	var pages, err = d.ExportPages()
+	if err != nil {
+		return err
+	}
+
+	var (
+		chapters  = make([]*epubChapter, 0, len(pages))
+		byAnchor  = make(map[string]*epubChapter, len(pages))
+		byObject  = make(map[filesystem.Object]*epubChapter, len(pages))
+		images    = make([]*epubImage, 0)
+		imageURLs = make(map[string]*epubImage)
+	)
+
+	for i, page := range pages {
+		var chapter = &epubChapter{
+			page: page,
+			id:   fmt.Sprintf("chapter-%03d", i+1),
+			file: fmt.Sprintf("text/chapter-%03d.xhtml", i+1),
+		}
+		chapters = append(chapters, chapter)
+		byAnchor[page.Anchor] = chapter
+		byObject[page.Object] = chapter
+	}
+
+	var buf bytes.Buffer
+	var w = zip.NewWriter(&buf)
+
+	// The mimetype must be the first file in the archive and may not be compressed.
+	mimetype, err := w.CreateHeader(&zip.FileHeader{
+		Name:   "mimetype",
+		Method: zip.Store,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = mimetype.Write([]byte("application/epub+zip")); err != nil {
+		return err
+	}
+
+	css, err := d.chromaCSS(false)
+	if err != nil {
+		return err
+	}
+
+	var files = map[string][]byte{
+		"META-INF/container.xml": []byte(epubContainer),
+		"OEBPS/style.css":        append([]byte(epubStyle), css...),
+	}
+
+	for _, chapter := range chapters {
+		var content = string(chapter.page.Content)
+
+		// Links to anchors point to the chapter containing the anchor
+		content = epubHrefRegex.ReplaceAllStringFunc(content, func(s string) string {
+			var anchor = html.UnescapeString(epubHrefRegex.FindStringSubmatch(s)[1])
+			var pageAnchor, _, _ = strings.Cut(anchor, "--")
+			var target, ok = byAnchor[pageAnchor]
+			if !ok || target == chapter {
+				return s
+			}
+			return fmt.Sprintf(` href="%s#%s"`, path.Base(target.file), html.EscapeString(anchor))
+		})
+
+		// Include images referenced from the page
+		content = imgSrcRegex.ReplaceAllStringFunc(content, func(s string) string {
+			var match = imgSrcRegex.FindStringSubmatch(s)
+			var src = html.UnescapeString(match[2])
+
+			var img, ok = imageURLs[src]
+			if !ok {
+				var data, err = d.readImage(chapter.page.Object, src)
+				if err != nil {
+					return s
+				}
+
+				var ext = strings.ToLower(path.Ext(strings.SplitN(src, "?", 2)[0]))
+				img = &epubImage{
+					id:        fmt.Sprintf("image-%03d", len(images)+1),
+					file:      fmt.Sprintf("images/image-%03d%s", len(images)+1, ext),
+					mediaType: mime.TypeByExtension(ext),
+					data:      data,
+				}
+				if img.mediaType == "" {
+					img.mediaType = "application/octet-stream"
+				}
+				images = append(images, img)
+				imageURLs[src] = img
+			}
+
+			return match[1] + "../" + img.file + match[3]
+		})
+
+		files["OEBPS/"+chapter.file] = []byte(epubDocument(
+			chapter.page.Title, "../style.css", xhtmlContent(content),
+		))
+	}
+
+	var (
+		nav  bytes.Buffer
+		root = byObject[d.config.RootDirectory]
+	)
+	nav.WriteString("<nav epub:type=\"toc\" id=\"toc\">\n<h1>Contents</h1>\n<ol>\n<li>\n")
+	fmt.Fprintf(&nav, "<a href=\"%s\">%s</a>\n", root.file, html.EscapeString(root.page.Title))
+	writeEPUBNav(&nav, d.config.RootDirectory, byObject)
+	nav.WriteString("</li>\n</ol>\n</nav>\n")
+	files["OEBPS/nav.xhtml"] = []byte(epubDocument("Contents", "style.css", nav.String()))
+
+	for _, img := range images {
+		files["OEBPS/"+img.file] = img.data
+	}
+
+	files["OEBPS/content.opf"] = []byte(d.epubPackage(chapters, images))
+
+	// Write the files in a stable order
+	var names = make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			return err
+		}
+		if _, err = f.Write(files[name]); err != nil {
+			return err
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, buf.Bytes(), 0644)
+}
+
+// readImage reads an image referenced from the object.
+// The image is either a static asset or a file inside of the documentation tree.
+func (d *Doccer) readImage(from filesystem.Object, src string) ([]byte, error) {
+	src = strings.SplitN(src, "?", 2)[0]
+
+	var staticURL = d.AssetURL("")
+	staticURL = strings.TrimSuffix(strings.SplitN(staticURL, "?", 2)[0], "/")
+	if staticURL != "" && strings.HasPrefix(src, staticURL+"/") {
+		return fs.ReadFile(d.embedFS, strings.TrimPrefix(src, staticURL+"/"))
+	}
+
+	var obj, _, ok = d.resolveLink(from, src)
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	var t, isTemplate = obj.(*filesystem.Template)
+	if !isTemplate || t.IsTextFile() {
+		return nil, fs.ErrNotExist
+	}
+
+	return []byte(t.Content), nil
+}
+
+// epubPackage returns the package document of the EPUB
+func (d *Doccer) epubPackage(chapters []*epubChapter, images []*epubImage) string {
+	var (
+		b       strings.Builder
+		project = d.config.Project
+		hash    = sha1.Sum([]byte(project.Name + "\x00" + project.Version + "\x00" + project.Repository))
+	)
+
+	// Name based UUID, stable between exports of the same version
+	hash[6] = (hash[6] & 0x0f) | 0x50
+	hash[8] = (hash[8] & 0x3f) | 0x80
+
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	b.WriteString(`<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id" prefix="schema: http://schema.org/">` + "\n")
+	b.WriteString(`<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">` + "\n")
+	fmt.Fprintf(&b, "<dc:identifier id=\"book-id\">urn:uuid:%x-%x-%x-%x-%x</dc:identifier>\n", hash[0:4], hash[4:6], hash[6:8], hash[8:10], hash[10:16])
+	fmt.Fprintf(&b, "<dc:title>%s</dc:title>\n", html.EscapeString(project.Name))
+	b.WriteString("<dc:language>en</dc:language>\n")
+	if project.Version != "" {
+		fmt.Fprintf(&b, "<meta property=\"schema:version\">%s</meta>\n", html.EscapeString(project.Version))
+	}
+	if project.Repository != "" {
+		fmt.Fprintf(&b, "<dc:source>%s</dc:source>\n", html.EscapeString(project.Repository))
+	}
+	fmt.Fprintf(&b, "<meta property=\"dcterms:modified\">%s</meta>\n", time.Now().UTC().Format("2006-01-02T15:04:05Z"))
+	b.WriteString("</metadata>\n<manifest>\n")
+	b.WriteString(`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>` + "\n")
+	b.WriteString(`<item id="style" href="style.css" media-type="text/css"/>` + "\n")
+	for _, chapter := range chapters {
+		fmt.Fprintf(&b, "<item id=\"%s\" href=\"%s\" media-type=\"application/xhtml+xml\"/>\n", chapter.id, chapter.file)
+	}
+	for _, img := range images {
+		fmt.Fprintf(&b, "<item id=\"%s\" href=\"%s\" media-type=\"%s\"/>\n", img.id, img.file, img.mediaType)
+	}
+	b.WriteString("</manifest>\n<spine>\n")
+	for _, chapter := range chapters {
+		fmt.Fprintf(&b, "<itemref idref=\"%s\"/>\n", chapter.id)
+	}
+	b.WriteString("</spine>\n</package>\n")
+
+	return b.String()
+}
+
+// writeEPUBNav writes a nested list of the directory's children
+func writeEPUBNav(b *bytes.Buffer, dir *filesystem.TemplateDirectory, chapters map[filesystem.Object]*epubChapter) {
+	var items bytes.Buffer
+	dir.Subdirectories.ForEach(func(key string, v *filesystem.TemplateDirectory) bool {
+		var chapter, ok = chapters[v]
+		if !ok {
+			return true
+		}
+		fmt.Fprintf(&items, "<li>\n<a href=\"%s\">%s</a>\n", chapter.file, html.EscapeString(v.GetTitle()))
+		writeEPUBNav(&items, v, chapters)
+		items.WriteString("</li>\n")
+		return true
+	})
+	dir.Templates.ForEach(func(key string, v *filesystem.Template) bool {
+		var chapter, ok = chapters[v]
+		if !ok {
+			return true
+		}
+		fmt.Fprintf(&items, "<li><a href=\"%s\">%s</a></li>\n", chapter.file, html.EscapeString(v.GetTitle()))
+		return true
+	})
+
+	if items.Len() > 0 {
+		b.WriteString("<ol>\n")
+		b.Write(items.Bytes())
+		b.WriteString("</ol>\n")
+	}
+}
+
+// epubDocument wraps the body in an XHTML document
+func epubDocument(title, stylesheet, body string) string {
+	return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head>
+<title>%s</title>
+<link rel="stylesheet" type="text/css" href="%s"/>
+</head>
+<body>
+%s
+</body>
+</html>
+`, html.EscapeString(title), stylesheet, body)
+}
+
+// xhtmlContent makes rendered HTML acceptable as XHTML.
+// Void elements are closed and named entities unknown to XML are replaced.
+func xhtmlContent(content string) string {
+	content = voidTagRegex.ReplaceAllString(content, "<$1$2/>")
+	return strings.NewReplacer(
+		"&nbsp;", "&#160;",
+		"&copy;", "&#169;",
+		"&mdash;", "&#8212;",
+		"&ndash;", "&#8211;",
+		"&hellip;", "&#8230;",
+	).Replace(content)
+}
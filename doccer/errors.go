@@ -1,9 +1,46 @@
 package doccer
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 var (
 
 	// ErrNoConfig is returned when there is no config file
 	ErrNoConfig = fmt.Errorf("no config file found")
 )
+
+// ValidationError is a problem with a single key of the configuration
+type ValidationError struct {
+	File    string // Config file the key is defined in, empty if unknown
+	Line    int    // Line of the key in the config file, 0 if unknown
+	Key     string // Dotted path to the key, for example menu.items.1.path
+	Message string // Description of the problem
+}
+
+func (e *ValidationError) Error() string {
+	switch {
+	case e.File != "" && e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s: %s", e.File, e.Line, e.Key, e.Message)
+	case e.File != "":
+		return fmt.Sprintf("%s: %s: %s", e.File, e.Key, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.Key, e.Message)
+}
+
+// ValidationErrors holds all problems found while validating the configuration
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	var lines = make([]string, 0, len(e)+1)
+	if len(e) == 1 {
+		lines = append(lines, "invalid configuration:")
+	} else {
+		lines = append(lines, fmt.Sprintf("invalid configuration, %d problems:", len(e)))
+	}
+	for _, err := range e {
+		lines = append(lines, "  "+err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,227 @@
+package doccer
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+)
+
+// ExportOptions configures the export command
+type ExportOptions struct {
+	Format string // Format to export to
+	Output string // File to write the export to
+}
+
+// ExportPage is a single page of an export
+type ExportPage struct {
+	Object   filesystem.Object
+	Anchor   string
+	Title    string
+	Depth    int
+	Content  template.HTML
+	Headings []ExportHeading
+}
+
+// ExportHeading is a heading inside of an exported page
+type ExportHeading struct {
+	ID    string
+	Title string
+	Level int
+}
+
+// exportContext is passed to the print template
+type exportContext struct {
+	Config *Config
+	Pages  []*ExportPage
+}
+
+var (
+	headingRegex = regexp.MustCompile(`(?s)<h([1-6])([^>]*)\sid="([^"]*)"([^>]*)>(.*?)</h[1-6]>`)
+	idRegex      = regexp.MustCompile(`\sid="([^"]*)"`)
+	hrefRegex    = regexp.MustCompile(`\shref="([^"]*)"`)
+	tagRegex     = regexp.MustCompile(`<[^>]+>`)
+	slugRegex    = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+func init() {
+	hooks.Register("parse_args", 0, func(d *Doccer, fs *flag.FlagSet) ParseFlagFn {
+		var (
+			format = fs.String("format", "", "export format (html, epub) or lint output format (text, json)")
+			out    = fs.String("o", "", "file to export to")
+		)
+		return func(d *Doccer, fs *flag.FlagSet) error {
+			d.exportOptions.Format = *format
+			d.exportOptions.Output = *out
+			return nil
+		}
+	})
+}
+
+// Export exports the documentation to a single file
+func (d *Doccer) Export() error {
+	var opts = d.exportOptions
+	if opts.Format == "" {
+		opts.Format = "html"
+	}
+
+	var exporter func(string) error
+	var extension string
+	switch strings.ToLower(opts.Format) {
+	case "html", "single-page":
+		exporter, extension = d.ExportSinglePage, "html"
+	case "epub":
+		exporter, extension = d.ExportEPUB, "epub"
+	default:
+		return fmt.Errorf("unknown export format: %s", opts.Format)
+	}
+
+	if opts.Output == "" {
+		opts.Output = fmt.Sprintf("%s.%s", clean_filename(d.config.Project.Name), extension)
+	}
+
+	if err := exporter(opts.Output); err != nil {
+		return err
+	}
+
+	fmt.Printf("Exported documentation to %s\n", opts.Output)
+	return nil
+}
+
+// ExportSinglePage writes every page of the documentation into one HTML file.
+//
+// Pages are written in reading order with a generated table of contents,
+// links between pages are turned into links to anchors inside of the document.
+func (d *Doccer) ExportSinglePage(file string) error {
+	var pages, err = d.ExportPages()
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	err = d.executeTemplate(&b, "print", &exportContext{
+		Config: d.config,
+		Pages:  pages,
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, b.Bytes(), 0644)
+}
+
+// ExportPages renders all pages in reading order.
+//
+// Heading IDs are namespaced per page and links to other
+// pages are rewritten to point to the page anchors.
+func (d *Doccer) ExportPages() ([]*ExportPage, error) {
+	var context, err = d.GetContext(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		objects = context.FlatObjectList()
+		pages   = make([]*ExportPage, 0, len(objects))
+		anchors = make(map[filesystem.Object]string, len(objects))
+	)
+
+	for _, o := range objects {
+		var obj = o.(*contextObject).Object
+		var page = &ExportPage{
+			Object: obj,
+			Anchor: exportAnchor(obj),
+			Title:  obj.GetTitle(),
+		}
+
+		var t *filesystem.Template
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			page.Depth = o.Depth
+			t = o.Index
+		case *filesystem.Template:
+			page.Depth = o.Depth
+			t = o
+		}
+
+		if t != nil {
+			ctx, err := d.GetContext(false)
+			if err != nil {
+				return nil, err
+			}
+			ctx.isExporting = true
+			if err = addTemplateContext(ctx, t); err != nil {
+				return nil, err
+			}
+			page.Content = ctx.Content
+		} else {
+			page.Content = template.HTML(fmt.Sprintf("<h1>%s</h1>", html.EscapeString(page.Title)))
+		}
+
+		anchors[obj] = page.Anchor
+		pages = append(pages, page)
+	}
+
+	for _, page := range pages {
+		page.Content = template.HTML(d.namespaceContent(page, string(page.Content), anchors))
+
+		for _, match := range headingRegex.FindAllStringSubmatch(string(page.Content), -1) {
+			var level = int(match[1][0] - '0')
+			if level > 2 {
+				continue
+			}
+			page.Headings = append(page.Headings, ExportHeading{
+				ID:    match[3],
+				Title: html.UnescapeString(tagRegex.ReplaceAllString(match[5], "")),
+				Level: level,
+			})
+		}
+	}
+
+	return pages, nil
+}
+
+// namespaceContent prefixes the heading IDs of a page with the page anchor
+// and rewrites links to other pages into links to their anchors.
+func (d *Doccer) namespaceContent(page *ExportPage, content string, anchors map[filesystem.Object]string) string {
+	content = idRegex.ReplaceAllStringFunc(content, func(s string) string {
+		var id = idRegex.FindStringSubmatch(s)[1]
+		return fmt.Sprintf(` id="%s--%s"`, page.Anchor, id)
+	})
+
+	return hrefRegex.ReplaceAllStringFunc(content, func(s string) string {
+		var href = html.UnescapeString(hrefRegex.FindStringSubmatch(s)[1])
+		var obj, fragment, ok = d.resolveLink(page.Object, href)
+		if !ok {
+			return s
+		}
+
+		var anchor, exists = anchors[obj]
+		if !exists {
+			return s
+		}
+
+		if fragment != "" {
+			anchor = fmt.Sprintf("%s--%s", anchor, fragment)
+		}
+
+		return fmt.Sprintf(` href="#%s"`, html.EscapeString(anchor))
+	})
+}
+
+// exportAnchor returns a unique anchor for the object inside of an export
+func exportAnchor(obj filesystem.Object) string {
+	var rel = strings.ToLower(objectRelative(obj))
+	rel = strings.Trim(slugRegex.ReplaceAllString(rel, "-"), "-")
+	if rel == "" {
+		rel = "index"
+	}
+	return "page-" + rel
+}
@@ -3,7 +3,7 @@ package filesystem
 import (
 	"fmt"
 	"io/fs"
-	"os"
+	"path"
 	"path/filepath"
 	"slices"
 	"strings"
@@ -24,6 +24,9 @@ type TemplateDirectory struct {
 
 	// Templates in the directory
 	Templates *orderedmap.Map[string, *Template] `json:"-"`
+
+	// The _partials directory of the root, kept out of the tree and navigation
+	Partials *TemplateDirectory `json:"-"`
 }
 
 func NewDirectory(dir *TemplateDirectory, name string) (*TemplateDirectory, error) {
@@ -39,7 +42,7 @@ func NewDirectory(dir *TemplateDirectory, name string) (*TemplateDirectory, erro
 			Root:          dir.Root,
 			Depth:         dir.Depth + 1,
 			RootDirectory: dir.RootDirectory,
-			Path:          filepath.Join(dir.Path, name),
+			Path:          path.Join(dir.Path, name),
 		},
 		Subdirectories: orderedmap.New[string, *TemplateDirectory](),
 		Templates:      orderedmap.New[string, *Template](),
@@ -49,7 +52,8 @@ func NewDirectory(dir *TemplateDirectory, name string) (*TemplateDirectory, erro
 }
 
 // NewTemplateDirectory creates a new template directory
-func NewTemplateDirectory(rootDir *TemplateDirectory, name, root, path, output, relative string, depth int) (*TemplateDirectory, error) {
+// The directory is read from dirPath inside of fileSys.
+func NewTemplateDirectory(fileSys fs.FS, rootDir *TemplateDirectory, name, root, dirPath, output, relative string, depth int) (*TemplateDirectory, error) {
 
 	if relative == "" && name != "" {
 		relative = name
@@ -59,7 +63,7 @@ func NewTemplateDirectory(rootDir *TemplateDirectory, name, root, path, output,
 		FSBase: FSBase{
 			Name:          name,
 			Root:          root,
-			Path:          path,
+			Path:          dirPath,
 			Output:        output,
 			Relative:      relative,
 			Depth:         depth,
@@ -73,7 +77,7 @@ func NewTemplateDirectory(rootDir *TemplateDirectory, name, root, path, output,
 		rootDir = dir
 	}
 
-	var dirs, err = os.ReadDir(path)
+	var dirs, err = fs.ReadDir(fileSys, dirPath)
 	if err != nil {
 		return nil, err
 	}
@@ -97,20 +101,25 @@ func NewTemplateDirectory(rootDir *TemplateDirectory, name, root, path, output,
 	for _, d := range dirs {
 
 		var (
-			fPath = filepath.Join(path, d.Name())
+			fPath = path.Join(dirPath, d.Name())
 			oPath = filepath.Join(output, d.Name())
 			rPath = filepath.Join(relative, d.Name())
 		)
 
 		if d.IsDir() {
-			var subDir, err = NewTemplateDirectory(rootDir, d.Name(), root, fPath, oPath, rPath, dir.Depth+1)
+			var subDir, err = NewTemplateDirectory(fileSys, rootDir, d.Name(), root, fPath, oPath, rPath, dir.Depth+1)
 			if err != nil {
 				return nil, err
 			}
 
+			if dir.Depth == 0 && subDir.Name == PARTIALS_DIR {
+				dir.Partials = subDir
+				continue
+			}
+
 			dir.Subdirectories.Set(subDir.Name, subDir)
 		} else {
-			var template, err = NewTemplate(rootDir, d.Name(), root, fPath, oPath, rPath, dir.Depth+1)
+			var template, err = NewTemplate(fileSys, rootDir, d.Name(), root, fPath, oPath, rPath, dir.Depth+1)
 			if err != nil {
 				return nil, err
 			}
@@ -160,6 +169,20 @@ func (t *TemplateDirectory) GetTitle() string {
 	return t.Index.Title
 }
 
+// GetOrder returns the position of the directory among its siblings.
+// This is set with the Order directive of the index.
+func (t *TemplateDirectory) GetOrder() int {
+	if t.Index == nil {
+		return 0
+	}
+	return t.Index.Order
+}
+
+// IsOrdered reports if the position of the directory is set with the Order directive of the index
+func (t *TemplateDirectory) IsOrdered() bool {
+	return t.Index != nil && t.Index.Ordered
+}
+
 // GetNext returns the next object in the directory
 func (d *TemplateDirectory) GetNext() Object {
 	return d.Index.GetNext()
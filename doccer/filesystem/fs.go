@@ -16,8 +16,14 @@ var (
 
 	// ErrFileExists is returned when a file or directory being added to the tree already exists
 	ErrFileExists = errors.New("file or directory already exists")
+
+	// ErrUnsupportedSource is returned when a documentation source cannot be opened
+	ErrUnsupportedSource = errors.New("unsupported documentation source, expected a directory or .zip archive")
 )
 
+// Directory in the root holding pages which are only included into other pages
+const PARTIALS_DIR = "_partials"
+
 type (
 	TextFileHookFunc func(name string, content []byte) bool
 )
@@ -70,7 +76,7 @@ type Object interface {
 }
 
 type FSBase struct {
-	// Absolute directory path
+	// Path inside of the documentation filesystem
 	Path string `json:"path"`
 
 	// Documentation root directory
@@ -96,6 +102,9 @@ type Config struct {
 	Title    string   // Title of the object
 	Next     []string // Path to the next object
 	Previous []string // Path to the previous object
+	Order    int      // Position among the other objects in the directory
+	Ordered  bool     // Whether the position is set with the Order directive
+	Tags     []string // Tags of the object
 	t        *FSBase
 }
 
@@ -125,6 +134,16 @@ func (t *Config) GetTitle() string {
 	return t.t.Name
 }
 
+// GetOrder returns the position of the template among its siblings
+func (t *Config) GetOrder() int {
+	return t.Order
+}
+
+// IsOrdered reports if the position of the template is set with the Order directive
+func (t *Config) IsOrdered() bool {
+	return t.Ordered
+}
+
 // GetNext returns the next object for the template
 func (d *Config) GetNext() Object {
 	var next, ok = d.t.RootDirectory.Walk(d.Next)
@@ -0,0 +1,122 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"slices"
+	"strings"
+)
+
+// OverlayFS combines multiple filesystems into one.
+//
+// Files in earlier layers take precedence over files in later layers,
+// the entries of directories which exist in multiple layers are merged.
+type OverlayFS []fs.FS
+
+// Overlay creates a new overlay of the given filesystems
+func Overlay(layers ...fs.FS) OverlayFS {
+	return OverlayFS(layers)
+}
+
+// Open opens the named file from the first layer it exists in
+func (o OverlayFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	for _, layer := range o {
+		var f, err = layer.Open(name)
+		if err == nil {
+			return f, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadDir reads the merged entries of the named directory, sorted by name
+func (o OverlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	var (
+		found   bool
+		seen    = make(map[string]struct{})
+		entries = make([]fs.DirEntry, 0)
+	)
+
+	for _, layer := range o {
+		var list, err = fs.ReadDir(layer, name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		found = true
+		for _, entry := range list {
+			if _, ok := seen[entry.Name()]; ok {
+				continue
+			}
+			seen[entry.Name()] = struct{}{}
+			entries = append(entries, entry)
+		}
+	}
+
+	if !found {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+
+	return entries, nil
+}
+
+// OpenFS opens a documentation source on disk.
+//
+// Directories are opened with os.DirFS, files ending in .zip are read
+// into memory and opened as a zip archive.
+func OpenFS(root string) (fs.FS, error) {
+	var info, err = os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return os.DirFS(root), nil
+	}
+
+	if !strings.HasSuffix(strings.ToLower(root), ".zip") {
+		return nil, &fs.PathError{Op: "open", Path: root, Err: ErrUnsupportedSource}
+	}
+
+	b, err := os.ReadFile(root)
+	if err != nil {
+		return nil, err
+	}
+
+	return zip.NewReader(bytes.NewReader(b), int64(len(b)))
+}
+
+// NewRootDirectory creates the root of a documentation tree from a filesystem.
+//
+// The root is used for display purposes and as the base of each object's Root,
+// output is the directory the tree will be written to when building.
+func NewRootDirectory(fileSys fs.FS, name, root, output string) (*TemplateDirectory, error) {
+	var dir, err = NewTemplateDirectory(fileSys, nil, "", root, ".", output, "", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	dir.Name = name
+	dir.Root = root
+	dir.Output = output
+	return dir, nil
+}
@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"html/template"
 	"io"
-	"os"
+	"io/fs"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	text_template "text/template"
+	"unicode"
 
 	"github.com/Nigel2392/doccer/doccer/render"
 )
@@ -23,7 +25,12 @@ type Template struct {
 	Config `json:",inline"`
 
 	// Template content
-	Content       string `json:"content"`
+	Content string `json:"content"`
+
+	// Line of the source file the content starts on
+	ContentLine int `json:"content_line"`
+
+	source        string
 	isTextFile    bool
 	canBeTemplate bool
 	loaded        bool
@@ -70,11 +77,12 @@ func NewDirectoryChild(dir *TemplateDirectory, name string, content []byte) (*Te
 }
 
 // NewTemplate creates a new template
-func NewTemplate(rootDir *TemplateDirectory, name, root, filepath, output, relative string, depth int) (*Template, error) {
+// The template is read from filePath inside of fileSys.
+func NewTemplate(fileSys fs.FS, rootDir *TemplateDirectory, name, root, filePath, output, relative string, depth int) (*Template, error) {
 	var template = &Template{
 		FSBase: FSBase{
 			Name:          name,
-			Path:          filepath,
+			Path:          filePath,
 			Root:          root,
 			Output:        output,
 			Relative:      relative,
@@ -88,7 +96,7 @@ func NewTemplate(rootDir *TemplateDirectory, name, root, filepath, output, relat
 	)
 
 	// Load the template content
-	var content, err = os.ReadFile(template.Path)
+	var content, err = fs.ReadFile(fileSys, template.Path)
 	if err != nil {
 		return nil, err
 	}
@@ -133,6 +141,10 @@ func (t *Template) loadContent(content []byte) error {
 
 	if t.isTextFile {
 
+		// Keep track of the lines removed from the start of the file
+		var trimmed = bytes.TrimLeftFunc(content, unicode.IsSpace)
+		t.ContentLine = bytes.Count(content[:len(content)-len(trimmed)], []byte("\n")) + 1
+
 		content = bytes.TrimSpace(content)
 
 		var (
@@ -173,6 +185,20 @@ func (t *Template) loadContent(content []byte) error {
 				t.Next = strings.Split(value, "/")
 			case "previous":
 				t.Previous = strings.Split(value, "/")
+			case "tags":
+				t.Tags = t.Tags[:0]
+				for _, tag := range strings.Split(value, ",") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						t.Tags = append(t.Tags, tag)
+					}
+				}
+			case "order":
+				var order, err = strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid order for %s: %s", t.Path, value)
+				}
+				t.Order = order
+				t.Ordered = true
 			default:
 				contentIndex = i
 				break loop
@@ -182,35 +208,78 @@ func (t *Template) loadContent(content []byte) error {
 		t.Content = string(bytes.Join(
 			lines[contentIndex:], []byte("\n"),
 		))
+		t.ContentLine += contentIndex
 	} else {
 		t.Content = string(content)
 	}
 
+	t.source = t.Content
+
 	return nil
 }
 
+// Source returns the content of the template before it was rendered.
+// Directives at the start of the file are not included.
+func (t *Template) Source() string {
+	return t.source
+}
+
 // Render the template
 func (t *Template) Render(w io.Writer, funcs template.FuncMap, context interface{}) error {
 	var renderfn = render.For(t.GetName())
 
 	if !t.loaded && t.canBeTemplate {
-		var tpl = text_template.New("content")
-
-		tpl = tpl.Funcs(funcs)
-		tpl, err := tpl.Parse(t.Content)
+		var content, err = execute(t.Content, funcs, context)
 		if err != nil {
 			return err
 		}
 
-		var b bytes.Buffer
-		err = tpl.ExecuteTemplate(&b, "content", context)
+		t.Content = content
+		t.loaded = true
+	}
+
+	return renderfn(w, []byte(t.Content))
+}
+
+// RenderUncached renders the template without keeping the executed content,
+// the template is executed again the next time it is rendered.
+func (t *Template) RenderUncached(w io.Writer, funcs template.FuncMap, context interface{}) error {
+	var (
+		renderfn = render.For(t.GetName())
+		content  = t.Content
+		err      error
+	)
+
+	// Once loaded the content has been executed already
+	if t.loaded {
+		content = t.source
+	}
+
+	if t.canBeTemplate {
+		content, err = execute(content, funcs, context)
 		if err != nil {
 			return err
 		}
+	}
 
-		t.Content = b.String()
-		t.loaded = true
+	return renderfn(w, []byte(content))
+}
+
+// execute executes the content as a template
+func execute(content string, funcs template.FuncMap, context interface{}) (string, error) {
+	var tpl = text_template.New("content")
+
+	tpl = tpl.Funcs(funcs)
+	tpl, err := tpl.Parse(content)
+	if err != nil {
+		return "", err
 	}
 
-	return renderfn(w, []byte(t.Content))
+	var b bytes.Buffer
+	err = tpl.ExecuteTemplate(&b, "content", context)
+	if err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
 }
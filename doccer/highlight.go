@@ -0,0 +1,84 @@
+package doccer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Stylesheet with the highlighting styles, generated from the configured chroma styles
+const CHROMA_CSS = "static/chroma.css"
+
+// Default styles used for highlighting code blocks
+const (
+	DEFAULT_HIGHLIGHT_STYLE      = "github"
+	DEFAULT_HIGHLIGHT_DARK_STYLE = "monokai"
+)
+
+var cssRuleRegex = regexp.MustCompile(`^(/\*.*?\*/\s*)?([^{]+)(\{.*)$`)
+
+// chromaCSS returns the stylesheet for highlighted code blocks.
+// The dark style is used when the browser prefers a dark color scheme,
+// or when the html element has data-theme="dark".
+func (d *Doccer) chromaCSS(dark bool) ([]byte, error) {
+	var light, err = chromaStyleCSS(d.config.Highlight.Style, "")
+	if err != nil || !dark || d.config.Highlight.DarkStyle == "" {
+		return light, err
+	}
+
+	var b bytes.Buffer
+	b.Write(light)
+
+	media, err := chromaStyleCSS(d.config.Highlight.DarkStyle, `:root:not([data-theme="light"])`)
+	if err != nil {
+		return nil, err
+	}
+	b.WriteString("@media (prefers-color-scheme: dark) {\n")
+	b.Write(media)
+	b.WriteString("}\n")
+
+	selected, err := chromaStyleCSS(d.config.Highlight.DarkStyle, `:root[data-theme="dark"]`)
+	if err != nil {
+		return nil, err
+	}
+	b.Write(selected)
+
+	return b.Bytes(), nil
+}
+
+// chromaStyleCSS returns the CSS of the chroma style with every rule prefixed by the scope
+func chromaStyleCSS(name, scope string) ([]byte, error) {
+	var style, ok = styles.Registry[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown highlight style %q", name)
+	}
+
+	var css bytes.Buffer
+	if err := chromahtml.New(chromahtml.WithClasses(true)).WriteCSS(&css, style); err != nil {
+		return nil, err
+	}
+
+	if scope == "" {
+		return css.Bytes(), nil
+	}
+
+	var (
+		b       bytes.Buffer
+		scanner = bufio.NewScanner(&css)
+	)
+	for scanner.Scan() {
+		var line = scanner.Text()
+		if match := cssRuleRegex.FindStringSubmatch(line); match != nil {
+			line = fmt.Sprintf("%s%s %s%s", match[1], scope, match[2], match[3])
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	return b.Bytes(), scanner.Err()
+}
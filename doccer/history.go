@@ -0,0 +1,164 @@
+package doccer
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+)
+
+// PageHistory holds when and by whom a page was last changed
+type PageHistory struct {
+	Updated      time.Time // Time of the last change
+	Author       string    // Author of the last change, empty if unknown
+	Contributors []string  // Everyone who changed the page, most recent first
+}
+
+// gitHistory is the git_history feature.
+// The history of all files in a layer of the input is read with a single git log when building or on first use.
+type gitHistory struct {
+	mu      sync.Mutex
+	history map[string]map[string]*PageHistory // History of the files by the root of their layer
+}
+
+func init() {
+	hooks.Register(
+		"register_features", 0,
+		func(d *Doccer, c *Config) Feature {
+			return &gitHistory{}
+		},
+	)
+}
+
+func (g *gitHistory) ID() string {
+	return "git_history"
+}
+
+func (g *gitHistory) Init(d *Doccer, c *Config) error {
+	hooks.Register("before_build", 0, func(d *Doccer) error {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+		g.history = nil
+		return nil
+	})
+
+	hooks.Register("pre_render_object", 0, func(d *Doccer, c *Context, obj filesystem.Object) error {
+		c.History = g.get(d.config, obj)
+		return nil
+	})
+
+	return nil
+}
+
+// get returns the history of the source file of the object.
+// Files are looked up in the layer of the input they are read from,
+// files unknown to git fall back to their modification time.
+func (g *gitHistory) get(c *Config, obj filesystem.Object) *PageHistory {
+	var t *filesystem.Template
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		t = o.Index
+	case *filesystem.Template:
+		t = o
+	}
+
+	if t == nil {
+		return nil
+	}
+
+	var root = t.Root
+	if root == "" {
+		root = c.Project.InputDirectory
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.history == nil {
+		g.history = make(map[string]map[string]*PageHistory)
+	}
+
+	var history, ok = g.history[root]
+	if !ok {
+		history = readGitHistory(root)
+		g.history[root] = history
+	}
+
+	if h, ok := history[t.Path]; ok {
+		return h
+	}
+
+	var info, err = os.Stat(filepath.Join(root, filepath.FromSlash(t.Path)))
+	if err != nil || info.IsDir() {
+		return nil
+	}
+
+	return &PageHistory{
+		Updated: info.ModTime(),
+	}
+}
+
+// readGitHistory reads the history of all files in the directory with one git log.
+// The keys of the map are slash separated paths relative to the directory.
+// An empty map is returned if the directory is not inside of a git repository.
+func readGitHistory(dir string) map[string]*PageHistory {
+	var history = make(map[string]*PageHistory)
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return history
+	}
+
+	var cmd = exec.Command(
+		"git", "-c", "core.quotePath=false", "-C", dir,
+		"log", "--format=%x1e%ct%x1f%an", "--name-only", "--relative", "--", ".",
+	)
+
+	var out, err = cmd.Output()
+	if err != nil {
+		return history
+	}
+
+	var (
+		scanner = bufio.NewScanner(bytes.NewReader(out))
+		updated time.Time
+		author  string
+	)
+
+	// Commits are listed newest first, followed by the files they changed
+	for scanner.Scan() {
+		var line = scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		if header, ok := strings.CutPrefix(line, "\x1e"); ok {
+			var timestamp, name, _ = strings.Cut(header, "\x1f")
+			var seconds, _ = strconv.ParseInt(timestamp, 10, 64)
+			updated, author = time.Unix(seconds, 0), name
+			continue
+		}
+
+		var h, ok = history[line]
+		if !ok {
+			h = &PageHistory{
+				Updated: updated,
+				Author:  author,
+			}
+			history[line] = h
+		}
+
+		if author != "" && !slices.Contains(h.Contributors, author) {
+			h.Contributors = append(h.Contributors, author)
+		}
+	}
+
+	return history
+}
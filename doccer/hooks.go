@@ -5,8 +5,10 @@ import (
 	"flag"
 	"fmt"
 	"html/template"
+	"strings"
 
 	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/output"
 )
 
 type (
@@ -20,12 +22,20 @@ type (
 	FeatureHook       func(*Doccer, *Config) Feature
 	DoccerHook        func(*Doccer) error
 	LoadHook          func(*Doccer, *Config) error
+	SinkBuildHook     func(*Doccer, *Config, output.Sink) error
 	ConstructMenuHook func(*Doccer, *Menu)
 	RendererHook      func(*Context) Renderer
 	ParseFlagFn       func(*Doccer, *flag.FlagSet) error
 	ParseArgHook      func(d *Doccer, fs *flag.FlagSet) ParseFlagFn
 )
 
+// RendererFunc is a function rendering HTML, it implements Renderer
+type RendererFunc func(*Context) string
+
+func (f RendererFunc) Render(c *Context) string {
+	return f(c)
+}
+
 type templatePaths []string // TemplatePath represents a path to a template
 
 func TemplatePath(paths ...string) templatePaths {
@@ -34,7 +44,7 @@ func TemplatePath(paths ...string) templatePaths {
 
 func (t templatePaths) Render(c *Context) string {
 	var tpl = template.New("feature_template")
-	tpl.Funcs(c.Config.Instance.TemplateFuncs())
+	tpl.Funcs(c.Config.Instance.contextFuncs(c))
 	tpl, err := tpl.ParseFS(c.Config.Instance.embedFS, t...)
 	if err != nil {
 		fmt.Println(err)
@@ -96,6 +106,32 @@ func init() {
 		},
 	)
 
+	// Tab groups are turned into tabs by a script, only included on pages with tabs
+	hooks.Register(
+		"render_body_end", 0,
+		func(c *Context) Renderer {
+			if !strings.Contains(string(c.Content), `<div class="tabs`) {
+				return RendererFunc(func(c *Context) string { return "" })
+			}
+			return TemplatePath(
+				"templates/hooks/tabs.tmpl",
+			)
+		},
+	)
+
+	// Code blocks get a copy button from a script, only included on pages with code blocks
+	hooks.Register(
+		"render_body_end", 0,
+		func(c *Context) Renderer {
+			if !strings.Contains(string(c.Content), `<div class="code-block">`) {
+				return RendererFunc(func(c *Context) string { return "" })
+			}
+			return TemplatePath(
+				"templates/hooks/code_copy.tmpl",
+			)
+		},
+	)
+
 	hooks.Register(
 		"register_features", 0,
 		func(d *Doccer, c *Config) Feature {
@@ -0,0 +1,153 @@
+package doccer
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"html/template"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Nigel2392/doccer/doccer/output"
+)
+
+// Name of the icon sprite inside of the static directory
+const ICON_SPRITE = "static/icons.svg"
+
+var (
+	svgViewBoxRegex = regexp.MustCompile(`(?s)^\s*<svg[^>]*?\sviewBox="([^"]*)"[^>]*>(.*)</svg>\s*$`)
+)
+
+// iconCache holds the contents of icons which have been read
+// and the names of icons used while building with a sprite.
+type iconCache struct {
+	mu    sync.Mutex
+	icons map[string][]byte
+	used  map[string]struct{}
+}
+
+// readIcon reads the SVG of a bootstrap icon.
+// Icons are only read from the static files once.
+func (d *Doccer) readIcon(name string) ([]byte, error) {
+	d.icons.mu.Lock()
+	defer d.icons.mu.Unlock()
+
+	if svg, ok := d.icons.icons[name]; ok {
+		return svg, nil
+	}
+
+	var svg, err = fs.ReadFile(d.embedFS, fmt.Sprintf("static/bootstrap-icons/%s.svg", name))
+	if err != nil {
+		return nil, err
+	}
+
+	if d.icons.icons == nil {
+		d.icons.icons = make(map[string][]byte)
+	}
+	d.icons.icons[name] = svg
+	return svg, nil
+}
+
+// useIcon marks the icon to be included in the sprite
+func (d *Doccer) useIcon(name string) {
+	d.icons.mu.Lock()
+	defer d.icons.mu.Unlock()
+
+	if d.icons.used == nil {
+		d.icons.used = make(map[string]struct{})
+	}
+	d.icons.used[name] = struct{}{}
+}
+
+// iconSprite reports if icons are referenced from the sprite for this context.
+// The sprite is only written when building with locally served static files,
+// exports have no static files and inline the icons.
+func (d *Doccer) iconSprite(c *Context) bool {
+	return d.config.Server.IconSprite && !c.isServing && !c.isExporting && IsLocal(d.config.Server.StaticUrl)
+}
+
+// resetIcons forgets the icons used by an earlier build
+func (d *Doccer) resetIcons() {
+	d.icons.mu.Lock()
+	defer d.icons.mu.Unlock()
+	d.icons.used = nil
+}
+
+// iconSize returns the width and height for the sizing passed to the Icon function
+func iconSize(sizing []string) (w, h string, err error) {
+	switch len(sizing) {
+	case 0:
+		return "24", "24", nil
+	case 1:
+		var wh = strings.Split(sizing[0], "x")
+		if len(wh) == 2 {
+			return wh[0], wh[1], nil
+		}
+		return sizing[0], sizing[0], nil
+	}
+	return "", "", fmt.Errorf("Icon sizing has too many arguments: %v", sizing)
+}
+
+// spriteIcon returns an Icon function which references the icons from the sprite
+func (d *Doccer) spriteIcon(c *Context) func(name string, sizing ...string) template.HTML {
+	return func(name string, sizing ...string) template.HTML {
+		if name == "" {
+			return template.HTML("")
+		}
+
+		var w, h, err = iconSize(sizing)
+		if err != nil {
+			return template.HTML(fmt.Sprintf("Error: %s", err))
+		}
+
+		if _, err = d.readIcon(name); err != nil {
+			return template.HTML(fmt.Sprintf("Error rendering SVG: %s", err))
+		}
+
+		d.useIcon(name)
+
+		return template.HTML(fmt.Sprintf(
+			`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" fill="currentColor" class="bi bi-%s"><use href="%s#icon-%s"/></svg>`,
+			html.EscapeString(w), html.EscapeString(h), html.EscapeString(name),
+			html.EscapeString(c.AssetURL(ICON_SPRITE)), html.EscapeString(name),
+		))
+	}
+}
+
+// writeIconSprite writes a sprite with all icons used while building to the sink.
+//
+// The sprite is not fingerprinted, its content is only known once the pages
+// referencing it have been rendered.
+func (d *Doccer) writeIconSprite(sink output.Sink) error {
+	d.icons.mu.Lock()
+	var names = make([]string, 0, len(d.icons.used))
+	for name := range d.icons.used {
+		names = append(names, name)
+	}
+	d.icons.mu.Unlock()
+
+	slices.Sort(names)
+
+	var b bytes.Buffer
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" style="display: none">` + "\n")
+	for _, name := range names {
+		var svg, err = d.readIcon(name)
+		if err != nil {
+			return err
+		}
+
+		var match = svgViewBoxRegex.FindSubmatch(svg)
+		if match == nil {
+			return fmt.Errorf("icon %s is not a valid SVG", name)
+		}
+
+		fmt.Fprintf(&b, "<symbol id=\"icon-%s\" viewBox=\"%s\">%s</symbol>\n", name, match[1], bytes.TrimSpace(match[2]))
+	}
+	b.WriteString("</svg>\n")
+
+	return sink.WriteFile(path.Join(d.staticOutputDir(), ICON_SPRITE), b.Bytes())
+}
@@ -0,0 +1,302 @@
+package doccer
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/render"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+var (
+	snippetStartRegex = regexp.MustCompile(`snippet:start\s+([\w.-]+)`)
+	snippetEndRegex   = regexp.MustCompile(`snippet:end\b`)
+	lineRangeRegex    = regexp.MustCompile(`^(\d*)(-?)(\d*)$`)
+)
+
+// includeIndex records which templates include which files
+type includeIndex struct {
+	mu   sync.Mutex
+	deps map[string]map[string]bool // Included file to the paths of the templates including it
+}
+
+// add records the file as a dependency of the template
+func (i *includeIndex) add(file string, t *filesystem.Template) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.deps == nil {
+		i.deps = make(map[string]map[string]bool)
+	}
+
+	if i.deps[file] == nil {
+		i.deps[file] = make(map[string]bool)
+	}
+
+	i.deps[file][t.Path] = true
+}
+
+// clear removes the dependencies of the template, before it is rendered again
+func (i *includeIndex) clear(t *filesystem.Template) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for file, templates := range i.deps {
+		delete(templates, t.Path)
+		if len(templates) == 0 {
+			delete(i.deps, file)
+		}
+	}
+}
+
+// Dependents returns the paths of the templates which include the file, relative to the input directory
+func (d *Doccer) Dependents(file string) []string {
+	var abs, err = filepath.Abs(file)
+	if err != nil {
+		return nil
+	}
+
+	d.includes.mu.Lock()
+	defer d.includes.mu.Unlock()
+
+	var paths = make([]string, 0, len(d.includes.deps[abs]))
+	for p := range d.includes.deps[abs] {
+		paths = append(paths, p)
+	}
+	slices.Sort(paths)
+	return paths
+}
+
+// projectRoot returns the directory files can be included from.
+// This is the directory of the config file, or the working directory if there is none.
+func (d *Doccer) projectRoot() (string, error) {
+	if d.configPath == "" {
+		return filepath.Abs(".")
+	}
+	return filepath.Abs(filepath.Dir(d.configPath))
+}
+
+// includePath returns the absolute path of the file to include.
+// Paths are relative to the project root and may not point outside of it.
+func (d *Doccer) includePath(name string) (string, error) {
+	var root, err = d.projectRoot()
+	if err != nil {
+		return "", err
+	}
+
+	if filepath.IsAbs(name) {
+		return "", fmt.Errorf("include %s: path must be relative to the project root", name)
+	}
+
+	var file = filepath.Join(root, filepath.FromSlash(name))
+
+	// Symlinks are resolved so they cannot point outside of the project either
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(root); err == nil {
+		root = resolved
+	}
+
+	var rel, relErr = filepath.Rel(root, file)
+	if relErr != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("include %s: path is outside of the project root", name)
+	}
+
+	return file, nil
+}
+
+// readInclude reads the selected lines of the file.
+// The selection is a line range such as 10-20, 10- or 15, or the name of a region
+// marked with snippet:start name and snippet:end comments.
+func (d *Doccer) readInclude(name string, t *filesystem.Template, selection ...string) (string, error) {
+	var file, err = d.includePath(name)
+	if err != nil {
+		return "", err
+	}
+
+	if t != nil {
+		d.includes.add(file, t)
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("include %s: %s", name, err)
+	}
+
+	var lines = strings.Split(strings.TrimRight(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n"), "\n")
+	switch len(selection) {
+	case 0:
+	case 1:
+		if match := lineRangeRegex.FindStringSubmatch(selection[0]); match != nil && selection[0] != "" && selection[0] != "-" {
+			lines, err = selectLines(lines, match)
+		} else {
+			lines, err = selectRegion(lines, selection[0])
+		}
+		if err != nil {
+			return "", fmt.Errorf("include %s: %s", name, err)
+		}
+	default:
+		return "", fmt.Errorf("include %s: expected one line range or region, got %d", name, len(selection))
+	}
+
+	// Markers of nested regions are not part of the snippet
+	var content = make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !snippetStartRegex.MatchString(line) && !snippetEndRegex.MatchString(line) {
+			content = append(content, line)
+		}
+	}
+
+	return strings.Join(dedent(content), "\n"), nil
+}
+
+// selectLines returns the lines in the range, line numbers start at 1
+func selectLines(lines []string, match []string) ([]string, error) {
+	var start, end = 1, len(lines)
+	if match[1] != "" {
+		start, _ = strconv.Atoi(match[1])
+	}
+	if match[3] != "" {
+		end, _ = strconv.Atoi(match[3])
+	} else if match[2] == "" {
+		end = start
+	}
+
+	if start < 1 || start > len(lines) || end < start {
+		return nil, fmt.Errorf("invalid line range %s for %d lines", match[0], len(lines))
+	}
+
+	return lines[start-1 : min(end, len(lines))], nil
+}
+
+// selectRegion returns the lines between the start and end markers of the region
+func selectRegion(lines []string, region string) ([]string, error) {
+	var (
+		start = -1
+		depth = 0
+	)
+
+	for i, line := range lines {
+		if match := snippetStartRegex.FindStringSubmatch(line); match != nil {
+			if start == -1 && match[1] == region {
+				start = i + 1
+				continue
+			}
+			if start != -1 {
+				depth++
+			}
+			continue
+		}
+
+		if start != -1 && snippetEndRegex.MatchString(line) {
+			if depth == 0 {
+				return lines[start:i], nil
+			}
+			depth--
+		}
+	}
+
+	if start == -1 {
+		return nil, fmt.Errorf("region %q not found", region)
+	}
+	return nil, fmt.Errorf("region %q has no snippet:end", region)
+}
+
+// dedent removes the indentation shared by all non-empty lines
+func dedent(lines []string) []string {
+	var prefix string
+	var first = true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	var out = make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = strings.TrimPrefix(line, prefix)
+	}
+	return out
+}
+
+// snippetLanguage returns the highlight language for the file from its name
+func snippetLanguage(name string) string {
+	var lexer = lexers.Match(path.Base(filepath.ToSlash(name)))
+	if lexer == nil {
+		return ""
+	}
+
+	var config = lexer.Config()
+	if len(config.Aliases) > 0 {
+		return config.Aliases[0]
+	}
+	return strings.ToLower(config.Name)
+}
+
+// includeFuncs returns the Include and Snippet template functions.
+// Files included while rendering the template are recorded as its dependencies.
+//
+// Include returns the content of the file as-is, Snippet returns it as a highlighted code block.
+func (d *Doccer) includeFuncs(t *filesystem.Template) (include, snippet func(name string, selection ...string) (string, error)) {
+	include = func(name string, selection ...string) (string, error) {
+		return d.readInclude(name, t, selection...)
+	}
+
+	snippet = func(name string, selection ...string) (string, error) {
+		var content, err = d.readInclude(name, t, selection...)
+		if err != nil {
+			return "", err
+		}
+
+		var code = fencedCode(content, snippetLanguage(name))
+		if t == nil || isMarkdown(t.Name) {
+			return code, nil
+		}
+
+		// Other pages are not rendered as markdown, the code block is highlighted here
+		var b strings.Builder
+		if err = render.For("snippet.md")(&b, []byte(code)); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+
+	return include, snippet
+}
+
+// fencedCode returns the content as a fenced markdown code block
+func fencedCode(content, language string) string {
+	// The fence must be longer than any run of backticks in the content
+	var fence = "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+
+	return fmt.Sprintf("%s%s\n%s\n%s", fence, language, content, fence)
+}
+
+// isMarkdown reports if the file is rendered as markdown
+func isMarkdown(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return true
+	}
+	return false
+}
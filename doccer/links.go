@@ -0,0 +1,106 @@
+package doccer
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+)
+
+// resolveLink resolves a link found in the content of an object to the object it points to.
+//
+// Links may be relative to the object, root-absolute (with or without the base URL)
+// or point to the source file of the target. The fragment of the link is returned separately.
+func (d *Doccer) resolveLink(from filesystem.Object, href string) (obj filesystem.Object, fragment string, ok bool) {
+	if href == "" || !IsLocal(href) || strings.HasPrefix(href, "//") {
+		return nil, "", false
+	}
+
+	var u, err = url.Parse(href)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return nil, "", false
+	}
+
+	fragment = u.Fragment
+	if u.Path == "" {
+		return from, fragment, from != nil
+	}
+
+	var p = u.Path
+	if strings.HasPrefix(p, "/") {
+		var base = strings.TrimSuffix(d.config.Server.BaseURL, "/")
+		p = strings.TrimPrefix(p, base)
+	} else if from != nil {
+		p = path.Join(path.Dir(objectRelative(from)), p)
+	}
+
+	obj, ok = d.walkPath(p)
+	return obj, fragment, ok
+}
+
+// walkPath looks up an object by a slash separated path relative to the input root.
+// Both source paths (guide/install.md) and built paths (guide/install.html) are accepted.
+func (d *Doccer) walkPath(p string) (filesystem.Object, bool) {
+	p = strings.Trim(path.Clean("/"+p), "/")
+
+	var parts = []string{}
+	if p != "" {
+		parts = strings.Split(p, "/")
+	}
+
+	if obj, ok := d.config.RootDirectory.Walk(parts); ok {
+		return obj, true
+	}
+
+	// Built pages end in .html, look for the source file with the same base name.
+	var last = parts[len(parts)-1]
+	if !strings.HasSuffix(last, ".html") {
+		return nil, false
+	}
+
+	var parent, ok = d.config.RootDirectory.Walk(parts[:len(parts)-1])
+	if !ok || !parent.IsDirectory() {
+		return nil, false
+	}
+
+	var (
+		dir  = parent.(*filesystem.TemplateDirectory)
+		base = strings.TrimSuffix(last, ".html")
+	)
+
+	if base == "index" {
+		return dir, true
+	}
+
+	var found filesystem.Object
+	dir.Templates.ForEach(func(name string, t *filesystem.Template) bool {
+		if strings.TrimSuffix(name, path.Ext(name)) == base {
+			found = t
+			return false
+		}
+		return true
+	})
+
+	return found, found != nil
+}
+
+// objectRelative returns the slash separated path of an object relative to the input root.
+// Directories resolve to the path of their index file.
+func objectRelative(obj filesystem.Object) string {
+	if c, ok := obj.(*contextObject); ok {
+		obj = c.Object
+	}
+
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		if o.Index != nil {
+			return strings.ReplaceAll(o.Index.Relative, "\\", "/")
+		}
+		return strings.ReplaceAll(path.Join(o.Relative, "index.html"), "\\", "/")
+	case *filesystem.Template:
+		return strings.ReplaceAll(o.Relative, "\\", "/")
+	}
+
+	return ""
+}
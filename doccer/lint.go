@@ -0,0 +1,350 @@
+package doccer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/render"
+)
+
+// Severities of lint rules
+const (
+	LintError   = "error"
+	LintWarning = "warning"
+	LintOff     = "off"
+)
+
+// LintRules are the rules checked by the linter with their default severity
+var LintRules = map[string]string{
+	"missing-title":   LintError,   // Pages without a Title directive or level 1 heading
+	"skipped-heading": LintWarning, // Headings skipping a level, for example h2 to h4
+	"duplicate-title": LintWarning, // Pages sharing a title
+	"empty-directory": LintWarning, // Directories without any files
+	"unknown-file":    LintWarning, // Text files without a renderer for their extension
+	"missing-alt":     LintError,   // Images without alt text
+	"orphan-page":     LintWarning, // Pages which are not in the menu and not linked to
+}
+
+var (
+	markdownImageRegex = regexp.MustCompile(`!\[([^\]]*)\]\(`)
+	imgTagRegex        = regexp.MustCompile(`<img\b[^>]*>`)
+	altAttrRegex       = regexp.MustCompile(`\balt\s*=\s*("[^"]*[^"\s][^"]*"|'[^']*[^'\s][^']*')`)
+	sourceLinkRegex    = regexp.MustCompile(`\]\(([^)\s]+)|\bhref\s*=\s*"([^"]+)"`)
+)
+
+// LintIssue is a problem found by the linter
+type LintIssue struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (i *LintIssue) String() string {
+	if i.Line > 0 {
+		return fmt.Sprintf("%s:%d: %s: %s [%s]", i.File, i.Line, i.Severity, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", i.File, i.Severity, i.Message, i.Rule)
+}
+
+// linter gathers the issues found in the documentation
+type linter struct {
+	d      *Doccer
+	issues []*LintIssue
+}
+
+func (l *linter) report(rule string, obj filesystem.Object, line int, format string, args ...any) {
+	var severity = l.d.lintSeverity(rule)
+	if severity == LintOff {
+		return
+	}
+
+	l.issues = append(l.issues, &LintIssue{
+		Rule:     rule,
+		Severity: severity,
+		File:     sourceFile(obj),
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lintSeverity returns the configured severity of the rule
+func (d *Doccer) lintSeverity(rule string) string {
+	if severity, ok := d.config.Lint.Rules[rule]; ok {
+		return strings.ToLower(severity)
+	}
+	return LintRules[rule]
+}
+
+// Lint checks the documentation and writes the problems found to stdout.
+// An error is returned if any problem with the error severity was found.
+func (d *Doccer) Lint() error {
+	var issues, err = d.LintIssues()
+	if err != nil {
+		return err
+	}
+
+	var format = d.config.Lint.Format
+	if d.exportOptions.Format != "" {
+		// The -format flag is shared with the export command
+		format = d.exportOptions.Format
+	}
+
+	if err = writeLintIssues(os.Stdout, format, issues); err != nil {
+		return err
+	}
+
+	var errorCount = 0
+	for _, issue := range issues {
+		if issue.Severity == LintError {
+			errorCount++
+		}
+	}
+
+	if errorCount > 0 {
+		return fmt.Errorf("lint found %d error(s) and %d warning(s)", errorCount, len(issues)-errorCount)
+	}
+
+	return nil
+}
+
+// writeLintIssues writes the issues as text or JSON
+func writeLintIssues(w io.Writer, format string, issues []*LintIssue) error {
+	switch strings.ToLower(format) {
+	case "", "text":
+		for _, issue := range issues {
+			if _, err := fmt.Fprintln(w, issue); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "json":
+		var enc = json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(issues)
+	}
+	return fmt.Errorf("unknown lint format: %s", format)
+}
+
+// LintIssues returns the problems found in the documentation tree
+func (d *Doccer) LintIssues() ([]*LintIssue, error) {
+	var (
+		l      = &linter{d: d, issues: make([]*LintIssue, 0)}
+		titles = make(map[string][]filesystem.Object)
+	)
+
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			if o.Index == nil && o.Subdirectories.Len() == 0 && o.Templates.Len() == 0 {
+				l.report("empty-directory", o, 0, "directory %s is empty", o.Relative)
+			}
+			if o.Index != nil {
+				l.lintPage(o, o.Index, titles)
+			}
+		case *filesystem.Template:
+			if o.IsTextFile() {
+				l.lintPage(o, o, titles)
+			}
+		}
+		return true
+	})
+
+	for title, objects := range titles {
+		if len(objects) < 2 {
+			continue
+		}
+		for _, obj := range objects[1:] {
+			l.report("duplicate-title", obj, 0, "title %q is also used by %s", title, sourceFile(objects[0]))
+		}
+	}
+
+	var orphans, err = d.orphans()
+	if err != nil {
+		return nil, err
+	}
+	for _, obj := range orphans {
+		l.report("orphan-page", obj, 0, "%s is not in the menu and no page links to it", obj.GetTitle())
+	}
+
+	slices.SortStableFunc(l.issues, func(a, b *LintIssue) int {
+		if a.File != b.File {
+			return strings.Compare(a.File, b.File)
+		}
+		return a.Line - b.Line
+	})
+
+	return l.issues, nil
+}
+
+// lintPage checks the content of a single page
+func (l *linter) lintPage(obj filesystem.Object, t *filesystem.Template, titles map[string][]filesystem.Object) {
+	if !render.Registered(t.Name) {
+		l.report("unknown-file", t, 0, "no renderer for %s, the file is written as-is", path.Ext(t.Name))
+	}
+
+	var (
+		headings = render.Headings(t.Name, []byte(t.Source()))
+		hasH1    = false
+		previous = 0
+	)
+
+	for _, heading := range headings {
+		if heading.Level == 1 {
+			hasH1 = true
+		}
+
+		var line = 0
+		if heading.Line > 0 {
+			line = t.ContentLine + heading.Line - 1
+		}
+
+		if previous > 0 && heading.Level > previous+1 {
+			l.report("skipped-heading", t, line, "heading %q skips from h%d to h%d", heading.Title, previous, heading.Level)
+		}
+		previous = heading.Level
+	}
+
+	if t.Title == "" && !hasH1 {
+		l.report("missing-title", t, 0, "page has no Title directive or level 1 heading")
+	}
+
+	var title = strings.ToLower(obj.GetTitle())
+	titles[title] = append(titles[title], obj)
+
+	sourceLines(t, func(line int, text string) {
+		for _, match := range markdownImageRegex.FindAllStringSubmatch(text, -1) {
+			if strings.TrimSpace(match[1]) == "" {
+				l.report("missing-alt", t, line, "image has no alt text")
+			}
+		}
+		for _, tag := range imgTagRegex.FindAllString(text, -1) {
+			if !altAttrRegex.MatchString(tag) {
+				l.report("missing-alt", t, line, "image has no alt text")
+			}
+		}
+	})
+}
+
+// orphans returns the pages which can not be reached from the menu or any link.
+// Directories without an index are not pages, they link to all of their children.
+func (d *Doccer) orphans() ([]filesystem.Object, error) {
+	if d.config.Menu.Auto {
+		return nil, nil
+	}
+
+	var (
+		reached = make(map[filesystem.Object]bool)
+		queue   = []filesystem.Object{d.config.RootDirectory}
+	)
+
+	var menu, err = d.BuildMenu(false)
+	if err != nil {
+		return nil, err
+	}
+
+	footer, err := d.buildFooter(false)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLinks, err := d.buildMenuItems(d.config.HeaderLinks, d.config.RootDirectory, false, MAX_MENU_ITEMS_DEPTH)
+	if err != nil {
+		return nil, err
+	}
+
+	var addItems func(items []MenuItem)
+	addItems = func(items []MenuItem) {
+		for _, item := range items {
+			if item.object != nil {
+				queue = append(queue, item.object)
+			}
+			addItems(item.Items)
+		}
+	}
+	addItems(menu.Items)
+	addItems(footer.Items)
+	addItems(headerLinks)
+
+	for len(queue) > 0 {
+		var obj = queue[0]
+		queue = queue[1:]
+		if obj == nil || reached[obj] {
+			continue
+		}
+		reached[obj] = true
+
+		var t *filesystem.Template
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			if o.Index == nil {
+				o.Subdirectories.ForEach(func(key string, v *filesystem.TemplateDirectory) bool {
+					queue = append(queue, v)
+					return true
+				})
+				o.Templates.ForEach(func(key string, v *filesystem.Template) bool {
+					queue = append(queue, v)
+					return true
+				})
+				continue
+			}
+			t = o.Index
+		case *filesystem.Template:
+			t = o
+		}
+
+		if t == nil || !t.IsTextFile() {
+			continue
+		}
+
+		queue = append(queue, obj.GetNext(), obj.GetPrevious())
+
+		sourceLines(t, func(line int, text string) {
+			for _, match := range includePageRegex.FindAllStringSubmatch(text, -1) {
+				if linked, ok := d.walkPath(match[1]); ok {
+					queue = append(queue, linked)
+				}
+			}
+			for _, match := range sourceLinkRegex.FindAllStringSubmatch(text, -1) {
+				var href = match[1] + match[2]
+				if target, ok := strings.CutPrefix(href, "ref:"); ok {
+					if linked, _, err := d.lookupRef(target); err == nil {
+						queue = append(queue, linked)
+					}
+					continue
+				}
+				if linked, _, ok := d.resolveLink(obj, href); ok {
+					queue = append(queue, linked)
+				}
+			}
+		})
+	}
+
+	var orphans = make([]filesystem.Object, 0)
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		if reached[obj] {
+			return true
+		}
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			if o.Index != nil {
+				orphans = append(orphans, o)
+			}
+		case *filesystem.Template:
+			if o.IsTextFile() {
+				orphans = append(orphans, o)
+			}
+		}
+		return true
+	})
+
+	return orphans, nil
+}
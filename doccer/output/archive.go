@@ -0,0 +1,116 @@
+package output
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// archiveFile closes the underlying file after the archive is closed
+type archiveFile struct {
+	Sink
+	file *os.File
+}
+
+func (a *archiveFile) Close() error {
+	var err = a.Sink.Close()
+	if cerr := a.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func createArchive(target string, newArchive func(io.Writer) Sink) (Sink, error) {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return nil, err
+	}
+
+	var f, err = os.Create(target)
+	if err != nil {
+		return nil, err
+	}
+
+	return &archiveFile{
+		Sink: newArchive(f),
+		file: f,
+	}, nil
+}
+
+// Zip writes the output to a zip archive
+type Zip struct {
+	w *zip.Writer
+}
+
+// NewZip returns a sink which writes a zip archive to w
+func NewZip(w io.Writer) *Zip {
+	return &Zip{w: zip.NewWriter(w)}
+}
+
+func (z *Zip) WriteFile(name string, data []byte) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+
+	f, err := z.w.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	return err
+}
+
+func (z *Zip) Close() error {
+	return z.w.Close()
+}
+
+// TarGz writes the output to a gzip compressed tar archive
+type TarGz struct {
+	gz *gzip.Writer
+	w  *tar.Writer
+}
+
+// NewTarGz returns a sink which writes a .tar.gz archive to w
+func NewTarGz(w io.Writer) *TarGz {
+	var gz = gzip.NewWriter(w)
+	return &TarGz{
+		gz: gz,
+		w:  tar.NewWriter(gz),
+	}
+}
+
+func (t *TarGz) WriteFile(name string, data []byte) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+
+	err = t.w.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = t.w.Write(data)
+	return err
+}
+
+func (t *TarGz) Close() error {
+	if err := t.w.Close(); err != nil {
+		return err
+	}
+	return t.gz.Close()
+}
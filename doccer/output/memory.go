@@ -0,0 +1,86 @@
+package output
+
+import (
+	"io/fs"
+	"slices"
+	"sync"
+	"testing/fstest"
+	"time"
+)
+
+// Memory keeps the output in memory.
+//
+// It implements fs.FS, a build written to memory can be
+// served directly with http.FileServer(http.FS(m)).
+type Memory struct {
+	mu    sync.RWMutex
+	files fstest.MapFS
+}
+
+// NewMemory returns an empty in-memory sink
+func NewMemory() *Memory {
+	return &Memory{
+		files: make(fstest.MapFS),
+	}
+}
+
+func (m *Memory) WriteFile(name string, data []byte) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+
+	var b = make([]byte, len(data))
+	copy(b, data)
+
+	m.mu.Lock()
+	m.files[name] = &fstest.MapFile{
+		Data:    b,
+		Mode:    0644,
+		ModTime: time.Now(),
+	}
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Open opens the named file for reading
+func (m *Memory) Open(name string) (fs.File, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.files.Open(name)
+}
+
+// Files returns the names and contents of all written files
+func (m *Memory) Files() map[string][]byte {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var files = make(map[string][]byte, len(m.files))
+	for name, f := range m.files {
+		files[name] = f.Data
+	}
+	return files
+}
+
+// CopyTo writes all files to the sink, sorted by name
+func (m *Memory) CopyTo(sink Sink) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var names = make([]string, 0, len(m.files))
+	for name := range m.files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		if err := sink.WriteFile(name, m.files[name].Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package output
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrInvalidName is returned when a file name escapes the root of the output
+	ErrInvalidName = errors.New("invalid output file name")
+)
+
+// Sink is the destination of a build
+type Sink interface {
+	// WriteFile writes a file to the sink.
+	// The name is a slash separated path, relative to the root of the output.
+	WriteFile(name string, data []byte) error
+
+	// Close flushes any buffered data and closes the sink
+	Close() error
+}
+
+// Open opens a sink for the target.
+//
+// Targets ending in .zip, .tar.gz or .tgz are written as an archive,
+// anything else is treated as a directory.
+func Open(target string) (Sink, error) {
+	var lower = strings.ToLower(target)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return createArchive(target, func(w io.Writer) Sink {
+			return NewZip(w)
+		})
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return createArchive(target, func(w io.Writer) Sink {
+			return NewTarGz(w)
+		})
+	}
+	return NewDirectory(target)
+}
+
+// IsArchive reports if the target is written as an archive by Open.
+// Archives are replaced as a whole, files cannot be updated in place.
+func IsArchive(target string) bool {
+	var lower = strings.ToLower(target)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// cleanName validates and cleans a file name written to a sink
+func cleanName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = path.Clean(strings.TrimPrefix(name, "/"))
+	if name == "." || name == ".." || strings.HasPrefix(name, "../") {
+		return "", fmt.Errorf("%w: %s", ErrInvalidName, name)
+	}
+	return name, nil
+}
+
+// Directory writes the output to a directory on disk
+type Directory struct {
+	Root string
+}
+
+// NewDirectory creates the root directory and returns a sink writing to it
+func NewDirectory(root string) (*Directory, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &Directory{Root: root}, nil
+}
+
+func (d *Directory) WriteFile(name string, data []byte) error {
+	name, err := cleanName(name)
+	if err != nil {
+		return err
+	}
+
+	var p = filepath.Join(d.Root, filepath.FromSlash(name))
+	if err = os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(p, data, 0644)
+}
+
+func (d *Directory) Close() error {
+	return nil
+}
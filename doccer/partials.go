@@ -0,0 +1,148 @@
+package doccer
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+)
+
+var (
+	includeMarkerRegex  = regexp.MustCompile(`<!-- doccer:include "([^"]*)" "([^"]*)" -->`)
+	includePageRegex    = regexp.MustCompile(`\bIncludePage\s+"([^"]*)"`)
+	includeHeadingRegex = regexp.MustCompile(`(<h[1-6][^>]*\sid=")([^"]*)(")`)
+	includeAnchorRegex  = regexp.MustCompile(`(\shref="#)([^"]*)(")`)
+)
+
+// lookupPage returns the page to include by its path relative to the input root.
+// Paths starting with _partials/ are looked up in the partials directory.
+func (d *Doccer) lookupPage(name string) (*filesystem.Template, error) {
+	var (
+		obj filesystem.Object
+		ok  bool
+		p   = strings.Trim(path.Clean("/"+name), "/")
+	)
+
+	if rest, isPartial := strings.CutPrefix(p, filesystem.PARTIALS_DIR+"/"); isPartial {
+		if partials := d.config.RootDirectory.Partials; partials != nil {
+			obj, ok = partials.Walk(strings.Split(rest, "/"))
+		}
+	} else {
+		obj, ok = d.walkPath(p)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("page %s not found", name)
+	}
+
+	var t *filesystem.Template
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		t = o.Index
+	case *filesystem.Template:
+		t = o
+	}
+
+	if t == nil || !t.IsTextFile() {
+		return nil, fmt.Errorf("%s is not a page", name)
+	}
+
+	return t, nil
+}
+
+// includePage returns a marker for the page to include.
+// The marker is replaced with the rendered page once the including page is rendered,
+// the IDs of its headings are prefixed to keep them unique.
+//
+// The marker is surrounded by blank lines, so markdown keeps it as a block
+// instead of wrapping it in the paragraph it is used in.
+func (d *Doccer) includePage(name string, prefix ...string) (string, error) {
+	if strings.Contains(name, `"`) {
+		return "", fmt.Errorf("invalid page name %q", name)
+	}
+
+	var t, err = d.lookupPage(name)
+	if err != nil {
+		return "", err
+	}
+
+	var p = tagSlug(strings.TrimSuffix(t.Name, path.Ext(t.Name)))
+	if len(prefix) > 0 {
+		p = tagSlug(prefix[0])
+	}
+
+	return fmt.Sprintf("\n\n<!-- doccer:include \"%s\" \"%s\" -->\n\n", name, p), nil
+}
+
+// resolveIncludes replaces the include markers in the rendered content with the included pages.
+// The stack holds the pages being included, a page including itself returns an error.
+func resolveIncludes(c *Context, content string, stack []string) (string, error) {
+	var (
+		d    = c.Config.Instance
+		errs error
+	)
+
+	content = includeMarkerRegex.ReplaceAllStringFunc(content, func(s string) string {
+		if errs != nil {
+			return s
+		}
+
+		var match = includeMarkerRegex.FindStringSubmatch(s)
+		var t, err = d.lookupPage(match[1])
+		if err != nil {
+			errs = err
+			return s
+		}
+
+		var name = t.Path
+		for _, included := range stack {
+			if included == name {
+				errs = fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), name)
+				return s
+			}
+		}
+
+		var b bytes.Buffer
+		var funcs = d.contextFuncs(c)
+		funcs["Include"], funcs["Snippet"] = d.includeFuncs(t)
+		d.includes.clear(t)
+		if err = t.RenderUncached(&b, funcs, c); err != nil {
+			// Shown in place of the included page, unless serving or building strictly
+			var content, tplErr = templateErrorContent(c, t, err)
+			if tplErr != nil {
+				errs = tplErr
+			}
+			return content
+		}
+
+		html, err := resolveIncludes(c, b.String(), append(stack, name))
+		if err != nil {
+			errs = err
+			return s
+		}
+
+		return prefixIDs(html, match[2])
+	})
+
+	return content, errs
+}
+
+// prefixIDs prefixes the heading IDs and fragment links of included content
+func prefixIDs(content, prefix string) string {
+	if prefix == "" {
+		return content
+	}
+
+	var replace = func(re *regexp.Regexp) func(string) string {
+		return func(s string) string {
+			var match = re.FindStringSubmatch(s)
+			return match[1] + prefix + "-" + match[2] + match[3]
+		}
+	}
+
+	content = includeHeadingRegex.ReplaceAllStringFunc(content, replace(includeHeadingRegex))
+	return includeAnchorRegex.ReplaceAllStringFunc(content, replace(includeAnchorRegex))
+}
@@ -0,0 +1,44 @@
+package doccer
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/Nigel2392/doccer/doccer/output"
+)
+
+func TestIncludePageRendersPerPage(t *testing.T) {
+	var docs = fstest.MapFS{
+		"alpha.md":            {Data: []byte("# Alpha\n\n{{ IncludePage \"_partials/shared.md\" }}\n")},
+		"beta.md":             {Data: []byte("# Beta\n\n{{ IncludePage \"_partials/shared.md\" }}\n")},
+		"_partials/shared.md": {Data: []byte("Included on {{ .Object.GetTitle }}\n")},
+	}
+
+	var config = &Config{
+		Server:  ServerConfig{BaseURL: "/"},
+		Project: ProjectConfig{Name: "Test", Version: "1"},
+	}
+
+	var d, err = NewHandler(config, docs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sink = output.NewMemory()
+	if err = d.BuildTo(sink); err != nil {
+		t.Fatal(err)
+	}
+
+	var files = sink.Files()
+	for name, title := range map[string]string{"alpha.html": "alpha.md", "beta.html": "beta.md"} {
+		var content, ok = files[name]
+		if !ok {
+			t.Fatalf("%s was not written", name)
+		}
+
+		if !strings.Contains(string(content), "Included on "+title) {
+			t.Errorf("%s does not include the partial rendered for %s", name, title)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package doccer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/render"
+)
+
+var (
+	refLinkRegex   = regexp.MustCompile(`\]\(ref:([^)\s]*)\)`)
+	refFuncRegex   = regexp.MustCompile(`\bRef\s+"([^"]*)"`)
+	refAnchorRegex = regexp.MustCompile(`(?s)(<a\s[^>]*?href=")ref:([^"]*)("[^>]*>)(.*?)(</a>)`)
+)
+
+// RefError is returned when a reference to a page or heading cannot be resolved
+type RefError struct {
+	File    string // Source file containing the reference
+	Line    int    // Line of the reference, 0 if unknown
+	Target  string // The referenced page and heading
+	Message string // Why the reference could not be resolved
+}
+
+func (e *RefError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: invalid reference %q: %s", e.File, e.Line, e.Target, e.Message)
+	}
+	return fmt.Sprintf("%s: invalid reference %q: %s", e.File, e.Target, e.Message)
+}
+
+// lookupRef resolves a reference of the form path/to/page.md#heading-id.
+// The path is relative to the input root, the heading is optional.
+func (d *Doccer) lookupRef(target string) (filesystem.Object, *render.Heading, error) {
+	var p, id, _ = strings.Cut(target, "#")
+
+	var obj, ok = d.walkPath(p)
+	if !ok {
+		return nil, nil, fmt.Errorf("page %s not found", p)
+	}
+
+	if id == "" {
+		return obj, nil, nil
+	}
+
+	var t *filesystem.Template
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		t = o.Index
+	case *filesystem.Template:
+		t = o
+	}
+
+	if t != nil {
+		var headings = d.pageHeadings(t)
+		for _, heading := range headings {
+			if heading.ID == id {
+				return obj, &heading, nil
+			}
+		}
+
+		// Headings with icons or links can be referenced by the ID of their text,
+		// the link points to the ID of the rendered heading
+		for _, heading := range headings {
+			if heading.TextID == id {
+				return obj, &heading, nil
+			}
+		}
+	}
+
+	return nil, nil, fmt.Errorf("heading #%s not found in %s", id, p)
+}
+
+// headingIndex holds the headings of the rendered pages of a documentation tree
+type headingIndex struct {
+	mu        sync.Mutex
+	root      *filesystem.TemplateDirectory
+	pages     map[*filesystem.Template][]render.Heading
+	rendering map[*filesystem.Template]bool // Pages being rendered, to break cycles of references
+}
+
+// pageHeadings returns the headings of the rendered page, including the headings of included pages.
+// Heading IDs are taken from the output, so headings containing template calls get the ID they are linked with.
+// The headings of the source are returned if the page cannot be rendered.
+func (d *Doccer) pageHeadings(t *filesystem.Template) []render.Heading {
+	d.headings.mu.Lock()
+	if d.headings.root != d.config.RootDirectory {
+		d.headings.root = d.config.RootDirectory
+		d.headings.pages = make(map[*filesystem.Template][]render.Heading)
+		d.headings.rendering = make(map[*filesystem.Template]bool)
+	}
+
+	if headings, ok := d.headings.pages[t]; ok {
+		d.headings.mu.Unlock()
+		return headings
+	}
+
+	if d.headings.rendering[t] {
+		d.headings.mu.Unlock()
+		return render.Headings(t.Name, []byte(t.Source()))
+	}
+
+	d.headings.rendering[t] = true
+	d.headings.mu.Unlock()
+
+	var headings, err = d.renderHeadings(t)
+	if err != nil {
+		headings = render.Headings(t.Name, []byte(t.Source()))
+	}
+
+	d.headings.mu.Lock()
+	defer d.headings.mu.Unlock()
+
+	delete(d.headings.rendering, t)
+	if d.headings.pages != nil {
+		d.headings.pages[t] = headings
+	}
+	return headings
+}
+
+// renderHeadings renders the page on its own and returns its headings
+func (d *Doccer) renderHeadings(t *filesystem.Template) ([]render.Heading, error) {
+	var context, err = d.GetContext(false)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		b bytes.Buffer
+		f = d.contextFuncs(context)
+	)
+
+	f["Include"], f["Snippet"] = d.includeFuncs(t)
+	context.object = t
+
+	if err = t.RenderUncached(&b, f, context); err != nil {
+		return nil, err
+	}
+
+	var headings = render.Headings("index.html", b.Bytes())
+	for _, match := range includeMarkerRegex.FindAllStringSubmatch(b.String(), -1) {
+		var included, err = d.lookupPage(match[1])
+		if err != nil {
+			continue
+		}
+
+		for _, heading := range d.pageHeadings(included) {
+			if match[2] != "" {
+				heading.ID = match[2] + "-" + heading.ID
+				heading.TextID = match[2] + "-" + heading.TextID
+			}
+			headings = append(headings, heading)
+		}
+	}
+
+	return headings, nil
+}
+
+// checkRefs validates all references in the source of the template.
+// The errors point to the line of the reference in the source file.
+func (d *Doccer) checkRefs(t *filesystem.Template) error {
+	var errs = make([]error, 0)
+	sourceLines(t, func(line int, text string) {
+		var matches = append(
+			refLinkRegex.FindAllStringSubmatch(text, -1),
+			refFuncRegex.FindAllStringSubmatch(text, -1)...,
+		)
+
+		for _, match := range matches {
+			if _, _, err := d.lookupRef(match[1]); err != nil {
+				errs = append(errs, &RefError{
+					File:    sourceFile(t),
+					Line:    line,
+					Target:  match[1],
+					Message: err.Error(),
+				})
+			}
+		}
+	})
+	return errors.Join(errs...)
+}
+
+// sourceLines calls fn for every line in the source of the template with its line in the file.
+// Lines inside of fenced code blocks are examples and skipped.
+func sourceLines(t *filesystem.Template, fn func(line int, text string)) {
+	var fence string
+	for i, text := range strings.Split(t.Source(), "\n") {
+		var trimmed = strings.TrimSpace(text)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			fence = ""
+		case fence == "":
+			fn(t.ContentLine+i, text)
+		}
+	}
+}
+
+// sourceFile returns the path of the source file of the object for messages
+func sourceFile(obj filesystem.Object) string {
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		return filepath.Join(o.Root, filepath.FromSlash(o.Path))
+	case *filesystem.Template:
+		return filepath.Join(o.Root, filepath.FromSlash(o.Path))
+	}
+	return obj.GetName()
+}
+
+// ref returns the URL and title of the referenced page or heading
+func (c *Context) ref(target string) (url, title string, err error) {
+	var obj, heading, lookupErr = c.Config.Instance.lookupRef(target)
+	if lookupErr != nil {
+		return "", "", lookupErr
+	}
+
+	url = c.relative(ObjectURL(c.Config.Server.BaseURL, obj, c.isServing))
+	title = obj.GetTitle()
+	if heading != nil {
+		url = fmt.Sprintf("%s#%s", url, heading.ID)
+		title = heading.Title
+	}
+
+	return url, title, nil
+}
+
+// Ref returns the URL of the referenced page or heading, for example guide/install.md#proxy
+func (c *Context) Ref(target string) (string, error) {
+	var url, _, err = c.ref(target)
+	return url, err
+}
+
+// resolveRefLinks replaces ref: links in rendered content with the URL of their target.
+// Links without text get the title of the heading or page.
+func resolveRefLinks(c *Context, content string) (string, error) {
+	var errs = make([]error, 0)
+	content = refAnchorRegex.ReplaceAllStringFunc(content, func(s string) string {
+		var match = refAnchorRegex.FindStringSubmatch(s)
+		var url, title, err = c.ref(html.UnescapeString(match[2]))
+		if err != nil {
+			errs = append(errs, err)
+			return s
+		}
+
+		var text = match[4]
+		if strings.TrimSpace(text) == "" {
+			text = html.EscapeString(title)
+		}
+
+		return match[1] + html.EscapeString(url) + match[3] + text + match[5]
+	})
+	return content, errors.Join(errs...)
+}
@@ -0,0 +1,262 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// AdmonitionTypes are the types of admonitions with their default title.
+// Containers are only opened for known types.
+var AdmonitionTypes = map[string]string{
+	"note":      "Note",
+	"info":      "Info",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+	"danger":    "Danger",
+}
+
+var alertMarkerRegex = regexp.MustCompile(`^\s*\[!(\w+)\]\s*$`)
+
+// KindAdmonition is the node kind of admonitions
+var KindAdmonition = ast.NewNodeKind("Admonition")
+
+// Admonition is a callout block, such as a note or warning
+type Admonition struct {
+	ast.BaseBlock
+	AdmonitionType string // Type of the admonition, a key of AdmonitionTypes
+	Title          string // Title shown above the content, the default title of the type if empty
+
+	// Number of colons of the opening fence, 0 for alerts
+	fence int
+}
+
+// NewAdmonition returns a new admonition of the type
+func NewAdmonition(typ, title string) *Admonition {
+	return &Admonition{
+		AdmonitionType: typ,
+		Title:          title,
+	}
+}
+
+func (n *Admonition) Kind() ast.NodeKind {
+	return KindAdmonition
+}
+
+func (n *Admonition) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"AdmonitionType": n.AdmonitionType,
+		"Title":          n.Title,
+	}, nil)
+}
+
+// Admonitions is the goldmark extension for admonitions.
+//
+// It supports GitHub style alerts:
+//
+//	> [!NOTE]
+//	> Content of the note.
+//
+// And fenced containers with an optional title:
+//
+//	:::warning Be careful
+//	Content of the warning.
+//	:::
+var Admonitions goldmark.Extender = &admonitionExtension{}
+
+type admonitionExtension struct{}
+
+func (e *admonitionExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&admonitionParser{}, 150),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(&alertTransformer{}, 100),
+		),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&admonitionRenderer{}, 500),
+		),
+	)
+}
+
+// admonitionParser parses :::type containers
+type admonitionParser struct{}
+
+func (p *admonitionParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+// fenceLength returns the number of colons the line starts with and the rest of the line
+func fenceLength(line []byte) (int, string) {
+	var i = 0
+	for i < len(line) && line[i] == ':' {
+		i++
+	}
+	return i, strings.TrimSpace(string(line[i:]))
+}
+
+// isClosingFence reports if the next line closes a container opened with the number of colons.
+// Lines inside of fenced code blocks never close a container.
+func isClosingFence(reader text.Reader, pc parser.Context, fence int) bool {
+	for _, block := range pc.OpenedBlocks() {
+		if _, ok := block.Node.(*ast.FencedCodeBlock); ok {
+			return false
+		}
+	}
+
+	var line, _ = reader.PeekLine()
+	var w, pos = util.IndentWidth(line, reader.LineOffset())
+	if w >= 4 {
+		return false
+	}
+
+	var length, rest = fenceLength(line[pos:])
+	return length == fence && rest == ""
+}
+
+// skipLine advances the reader to the end of the line, leaving the newline if there is one
+func skipLine(reader text.Reader) {
+	var line, segment = reader.PeekLine()
+	var length = segment.Len()
+	if len(line) > 0 && line[len(line)-1] == '\n' {
+		length--
+	}
+	reader.Advance(length)
+}
+
+func (p *admonitionParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	var line, _ = reader.PeekLine()
+	var pos = pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+
+	var fence, rest = fenceLength(line[pos:])
+	if fence < 3 || rest == "" {
+		return nil, parser.NoChildren
+	}
+
+	var typ, title, _ = strings.Cut(rest, " ")
+	typ = strings.ToLower(typ)
+	if _, ok := AdmonitionTypes[typ]; !ok {
+		return nil, parser.NoChildren
+	}
+
+	var node = NewAdmonition(typ, strings.TrimSpace(title))
+	node.fence = fence
+
+	skipLine(reader)
+	return node, parser.HasChildren
+}
+
+func (p *admonitionParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	if isClosingFence(reader, pc, node.(*Admonition).fence) {
+		skipLine(reader)
+		return parser.Close
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *admonitionParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *admonitionParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *admonitionParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// alertTransformer turns blockquotes starting with [!TYPE] into admonitions
+type alertTransformer struct{}
+
+func (t *alertTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var (
+		source = reader.Source()
+		quotes = make([]*ast.Blockquote, 0)
+	)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if quote, ok := n.(*ast.Blockquote); ok && entering {
+			quotes = append(quotes, quote)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, quote := range quotes {
+		var paragraph, ok = quote.FirstChild().(*ast.Paragraph)
+		if !ok || paragraph.Lines().Len() == 0 {
+			continue
+		}
+
+		var marker = paragraph.Lines().At(0)
+		var match = alertMarkerRegex.FindSubmatch(marker.Value(source))
+		if match == nil {
+			continue
+		}
+
+		var typ = strings.ToLower(string(match[1]))
+		if _, ok := AdmonitionTypes[typ]; !ok {
+			continue
+		}
+
+		// Remove the marker from the paragraph, and the paragraph if nothing is left
+		for child := paragraph.FirstChild(); child != nil; {
+			var next = child.NextSibling()
+			if textNode, ok := child.(*ast.Text); ok && textNode.Segment.Stop <= marker.Stop {
+				paragraph.RemoveChild(paragraph, child)
+			}
+			child = next
+		}
+		if paragraph.ChildCount() == 0 {
+			quote.RemoveChild(quote, paragraph)
+		}
+
+		var admonition = NewAdmonition(typ, "")
+		for child := quote.FirstChild(); child != nil; {
+			var next = child.NextSibling()
+			admonition.AppendChild(admonition, child)
+			child = next
+		}
+
+		quote.Parent().ReplaceChild(quote.Parent(), quote, admonition)
+	}
+}
+
+// admonitionRenderer renders admonitions as callouts
+type admonitionRenderer struct{}
+
+func (r *admonitionRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindAdmonition, r.render)
+}
+
+func (r *admonitionRenderer) render(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	var n = node.(*Admonition)
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	var title = n.Title
+	if title == "" {
+		title = AdmonitionTypes[n.AdmonitionType]
+	}
+
+	fmt.Fprintf(w, "<div class=\"admonition admonition-%s\">\n", html.EscapeString(n.AdmonitionType))
+	fmt.Fprintf(w, "<p class=\"admonition-title\">%s</p>\n", html.EscapeString(title))
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,81 @@
+package render
+
+import (
+	"path"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// CodeBlock is a fenced code block of a document
+type CodeBlock struct {
+	Language   string   // Language of the code block, the first word of the info string
+	Attributes []string // Remaining words of the info string, for example "test"
+	Code       string   // Content of the code block
+	Line       int      // Line of the first line of code in the content
+}
+
+// HasAttribute reports if the info string of the code block contains the word
+func (b *CodeBlock) HasAttribute(name string) bool {
+	for _, attr := range b.Attributes {
+		if strings.EqualFold(attr, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// CodeBlocks returns the fenced code blocks of a markdown document in order.
+// Other documents have no code blocks.
+func CodeBlocks(filename string, content []byte) []CodeBlock {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown":
+	default:
+		return nil
+	}
+
+	var (
+		blocks = make([]CodeBlock, 0)
+		doc    = newMarkdown().Parser().Parse(text.NewReader(content))
+	)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		var block, ok = n.(*ast.FencedCodeBlock)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var lines = block.Lines()
+		if lines.Len() == 0 {
+			return ast.WalkSkipChildren, nil
+		}
+
+		var (
+			code strings.Builder
+			info []string
+		)
+		for i := 0; i < lines.Len(); i++ {
+			var segment = lines.At(i)
+			code.Write(segment.Value(content))
+		}
+
+		if block.Info != nil {
+			info = strings.Fields(string(block.Info.Segment.Value(content)))
+		}
+
+		var codeBlock = CodeBlock{
+			Code: code.String(),
+			Line: lineOf(content, lines.At(0).Start),
+		}
+		if len(info) > 0 {
+			codeBlock.Language = strings.ToLower(info[0])
+			codeBlock.Attributes = info[1:]
+		}
+
+		blocks = append(blocks, codeBlock)
+		return ast.WalkSkipChildren, nil
+	})
+
+	return blocks
+}
@@ -1,6 +1,8 @@
 package render
 
 import (
+	"fmt"
+	"html"
 	"io"
 
 	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
@@ -8,9 +10,22 @@ import (
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
 	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
-	"github.com/yuin/goldmark/renderer/html"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/util"
 )
 
+// Options configure how markdown is rendered
+type Options struct {
+	LineNumbers bool // Show line numbers in highlighted code blocks
+}
+
+var options Options
+
+// Configure sets the options used for rendering markdown
+func Configure(o Options) {
+	options = o
+}
+
 func renderRaw(w io.Writer, content []byte) error {
 	_, err := w.Write(content)
 	return err
@@ -30,24 +45,63 @@ func renderRaw(w io.Writer, content []byte) error {
 //	}
 
 func renderMarkdown(w io.Writer, content []byte) error {
-	md := goldmark.New(
+	return newMarkdown().Convert(content, w)
+}
+
+// newMarkdown returns the markdown converter used for rendering
+func newMarkdown() goldmark.Markdown {
+	return goldmark.New(
 		goldmark.WithExtensions(
 			extension.GFM,
+			Admonitions,
+			TabGroups,
 			highlighting.NewHighlighting(
-				highlighting.WithStyle("monokai"),
 				highlighting.WithFormatOptions(
-					chromahtml.WithLineNumbers(true),
+					chromahtml.WithClasses(true),
+					chromahtml.WithLineNumbers(options.LineNumbers),
 				),
+				highlighting.WithWrapperRenderer(codeBlockWrapper),
 			),
 		),
 		goldmark.WithParserOptions(
 			parser.WithAutoHeadingID(),
 		),
 		goldmark.WithRendererOptions(
-			html.WithHardWraps(),
-			html.WithXHTML(),
-			html.WithUnsafe(),
+			goldmarkhtml.WithHardWraps(),
+			goldmarkhtml.WithXHTML(),
+			goldmarkhtml.WithUnsafe(),
 		),
 	)
-	return md.Convert(content, w)
+}
+
+// codeBlockWrapper wraps code blocks in a container with an optional title,
+// set with {title="main.go"} in the info string.
+// Code blocks which are not highlighted are written as plain pre and code elements.
+func codeBlockWrapper(w util.BufWriter, c highlighting.CodeBlockContext, entering bool) {
+	if !entering {
+		if !c.Highlighted() {
+			_, _ = w.WriteString("</code></pre>\n")
+		}
+		_, _ = w.WriteString("</div>\n")
+		return
+	}
+
+	_, _ = w.WriteString("<div class=\"code-block\">\n")
+	if attrs := c.Attributes(); attrs != nil {
+		if title, ok := attrs.Get([]byte("title")); ok {
+			if b, ok := title.([]byte); ok && len(b) > 0 {
+				fmt.Fprintf(w, "<div class=\"code-block-title\">%s</div>\n", html.EscapeString(string(b)))
+			}
+		}
+	}
+
+	if c.Highlighted() {
+		return
+	}
+
+	if language, ok := c.Language(); ok {
+		fmt.Fprintf(w, "<pre><code class=\"language-%s\">", html.EscapeString(string(language)))
+	} else {
+		_, _ = w.WriteString("<pre><code>")
+	}
 }
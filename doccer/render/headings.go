@@ -0,0 +1,100 @@
+package render
+
+import (
+	"bytes"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	htmlHeadingRegex = regexp.MustCompile(`(?s)<h([1-6])[^>]*?\sid="([^"]*)"[^>]*>(.*?)</h[1-6]>`)
+	htmlTagRegex     = regexp.MustCompile(`<[^>]+>`)
+)
+
+// Heading is a heading of a document
+type Heading struct {
+	ID     string // ID of the heading, used as the anchor
+	TextID string // ID generated from the text of the heading alone, without the markup of icons and links
+	Title  string // Text of the heading
+	Level  int    // Level of the heading, 1 to 6
+	Line   int    // Line of the heading in the content, 0 if unknown
+}
+
+// Headings returns the headings of the document in order.
+// The IDs are the same as those generated when rendering the document.
+func Headings(filename string, content []byte) []Heading {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".md", ".markdown":
+		return markdownHeadings(content)
+	case ".html":
+		return htmlHeadings(content)
+	}
+	return nil
+}
+
+func markdownHeadings(content []byte) []Heading {
+	var (
+		headings = make([]Heading, 0)
+		doc      = newMarkdown().Parser().Parse(text.NewReader(content))
+	)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		var heading, ok = n.(*ast.Heading)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+
+		var line int
+		if heading.Lines().Len() > 0 {
+			line = lineOf(content, heading.Lines().At(0).Start)
+		}
+
+		var id, _ = heading.AttributeString("id")
+		if b, ok := id.([]byte); ok {
+			headings = append(headings, Heading{
+				ID:     string(b),
+				TextID: TextID(string(heading.Text(content))),
+				Title:  string(heading.Text(content)),
+				Level:  heading.Level,
+				Line:   line,
+			})
+		}
+
+		return ast.WalkSkipChildren, nil
+	})
+
+	return headings
+}
+
+func htmlHeadings(content []byte) []Heading {
+	var headings = make([]Heading, 0)
+	for _, idx := range htmlHeadingRegex.FindAllSubmatchIndex(content, -1) {
+		var title = strings.TrimSpace(html.UnescapeString(htmlTagRegex.ReplaceAllString(string(content[idx[6]:idx[7]]), "")))
+		headings = append(headings, Heading{
+			ID:     html.UnescapeString(string(content[idx[4]:idx[5]])),
+			TextID: TextID(title),
+			Title:  title,
+			Level:  int(content[idx[2]] - '0'),
+			Line:   lineOf(content, idx[0]),
+		})
+	}
+	return headings
+}
+
+// TextID returns the ID generated for a heading with only the text.
+// Headings containing icons or links get a different ID when rendered,
+// as the markup is part of the text the ID is generated from.
+func TextID(text string) string {
+	return string(parser.NewContext().IDs().Generate([]byte(text), ast.KindHeading))
+}
+
+// lineOf returns the line of the offset in the content
+func lineOf(content []byte, offset int) int {
+	return bytes.Count(content[:offset], []byte("\n")) + 1
+}
@@ -13,11 +13,12 @@ var (
 )
 
 func init() {
-	// Check if the file is a javascript, css or webassembly file
+	// Check if the file is a javascript, css, svg or webassembly file
 	hooks.Register("is_text_file", 99, func(name string, content []byte) bool {
 		name = strings.ToLower(name)
 		return !(strings.HasSuffix(name, ".js") ||
 			strings.HasSuffix(name, ".css") ||
+			strings.HasSuffix(name, ".svg") ||
 			strings.HasSuffix(name, ".wasm") ||
 			strings.HasSuffix(name, ".wat"))
 	})
@@ -36,6 +37,16 @@ func Register(filetype string, render func(io.Writer, []byte) error) {
 	renderMap[filetype] = render
 }
 
+// Registered reports if a renderer is registered for the file's extension
+func Registered(filename string) bool {
+	var ext = path.Ext(filename)
+	if ext == "" {
+		return false
+	}
+	_, ok := renderMap[ext[1:]]
+	return ok
+}
+
 func For(filename string) func(io.Writer, []byte) error {
 	var ext = path.Ext(filename)
 	if ext == "" {
@@ -0,0 +1,260 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var codeLabelRegex = regexp.MustCompile(`\[([^\]]+)\]`)
+
+var (
+	// KindTabs is the node kind of tab groups
+	KindTabs = ast.NewNodeKind("Tabs")
+
+	// KindTab is the node kind of a single tab
+	KindTab = ast.NewNodeKind("Tab")
+)
+
+// Tabs is a group of tabs, only one of which is shown at a time
+type Tabs struct {
+	ast.BaseBlock
+	CodeGroup bool // Every code block in the group is a tab
+
+	// Number of colons of the opening fence
+	fence int
+}
+
+func (n *Tabs) Kind() ast.NodeKind {
+	return KindTabs
+}
+
+func (n *Tabs) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"CodeGroup": fmt.Sprint(n.CodeGroup),
+	}, nil)
+}
+
+// Tab is a single tab of a group
+type Tab struct {
+	ast.BaseBlock
+	Label string // Label of the tab, tabs with the same label are selected together
+
+	// Number of colons of the opening fence, 0 for code blocks in a code group
+	fence int
+}
+
+func (n *Tab) Kind() ast.NodeKind {
+	return KindTab
+}
+
+func (n *Tab) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{
+		"Label": n.Label,
+	}, nil)
+}
+
+// TabGroups is the goldmark extension for tabbed content.
+//
+// Sections are grouped with tabs and tab containers:
+//
+//	::::tabs
+//	:::tab Linux
+//	Content for Linux.
+//	:::
+//	:::tab Windows
+//	Content for Windows.
+//	:::
+//	::::
+//
+// Every fenced code block in a code group is a tab, labelled with [label] in the info string or its language:
+//
+//	:::code-group
+//	```bash [npm]
+//	npm install
+//	```
+//	```bash [yarn]
+//	yarn install
+//	```
+//	:::
+var TabGroups goldmark.Extender = &tabsExtension{}
+
+type tabsExtension struct{}
+
+func (e *tabsExtension) Extend(m goldmark.Markdown) {
+	m.Parser().AddOptions(
+		parser.WithBlockParsers(
+			util.Prioritized(&tabsParser{}, 140),
+		),
+		parser.WithASTTransformers(
+			util.Prioritized(&codeGroupTransformer{}, 100),
+		),
+	)
+	m.Renderer().AddOptions(
+		renderer.WithNodeRenderers(
+			util.Prioritized(&tabsRenderer{}, 500),
+		),
+	)
+}
+
+// tabsParser parses tabs, tab and code-group containers
+type tabsParser struct{}
+
+func (p *tabsParser) Trigger() []byte {
+	return []byte{':'}
+}
+
+func (p *tabsParser) Open(parent ast.Node, reader text.Reader, pc parser.Context) (ast.Node, parser.State) {
+	var line, _ = reader.PeekLine()
+	var pos = pc.BlockOffset()
+	if pos < 0 {
+		return nil, parser.NoChildren
+	}
+
+	var fence, rest = fenceLength(line[pos:])
+	if fence < 3 || rest == "" {
+		return nil, parser.NoChildren
+	}
+
+	var (
+		name, label, _ = strings.Cut(rest, " ")
+		node           ast.Node
+	)
+
+	switch strings.ToLower(name) {
+	case "tabs":
+		node = &Tabs{fence: fence}
+	case "code-group":
+		node = &Tabs{fence: fence, CodeGroup: true}
+	case "tab":
+		// Tabs are only valid directly inside of a group
+		if _, ok := parent.(*Tabs); !ok || strings.TrimSpace(label) == "" {
+			return nil, parser.NoChildren
+		}
+		node = &Tab{fence: fence, Label: strings.TrimSpace(label)}
+	default:
+		return nil, parser.NoChildren
+	}
+
+	skipLine(reader)
+	return node, parser.HasChildren
+}
+
+func (p *tabsParser) Continue(node ast.Node, reader text.Reader, pc parser.Context) parser.State {
+	var fence int
+	switch n := node.(type) {
+	case *Tabs:
+		fence = n.fence
+	case *Tab:
+		fence = n.fence
+	}
+
+	if isClosingFence(reader, pc, fence) {
+		skipLine(reader)
+		return parser.Close
+	}
+
+	return parser.Continue | parser.HasChildren
+}
+
+func (p *tabsParser) Close(node ast.Node, reader text.Reader, pc parser.Context) {}
+
+func (p *tabsParser) CanInterruptParagraph() bool {
+	return true
+}
+
+func (p *tabsParser) CanAcceptIndentedLine() bool {
+	return false
+}
+
+// codeGroupTransformer wraps the code blocks of code groups in tabs
+type codeGroupTransformer struct{}
+
+func (t *codeGroupTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	var (
+		source = reader.Source()
+		groups = make([]*Tabs, 0)
+	)
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if group, ok := n.(*Tabs); ok && entering && group.CodeGroup {
+			groups = append(groups, group)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, group := range groups {
+		for child := group.FirstChild(); child != nil; {
+			var next = child.NextSibling()
+			if block, ok := child.(*ast.FencedCodeBlock); ok {
+				var tab = &Tab{Label: codeLabel(block, source)}
+				group.ReplaceChild(group, block, tab)
+				tab.AppendChild(tab, block)
+			}
+			child = next
+		}
+	}
+}
+
+// codeLabel returns the label of a code block from [label] in its info string before any attributes, or its language
+func codeLabel(block *ast.FencedCodeBlock, source []byte) string {
+	if block.Info != nil {
+		var info = block.Info.Segment.Value(source)
+		if i := bytes.IndexByte(info, '{'); i >= 0 {
+			info = info[:i]
+		}
+		if match := codeLabelRegex.FindSubmatch(info); match != nil {
+			return strings.TrimSpace(string(match[1]))
+		}
+	}
+
+	if language := block.Language(source); len(language) > 0 {
+		return string(language)
+	}
+
+	return "Code"
+}
+
+// tabsRenderer renders tab groups.
+// All tabs are rendered visible with their label, the script turns them into tabs.
+type tabsRenderer struct{}
+
+func (r *tabsRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(KindTabs, r.renderTabs)
+	reg.Register(KindTab, r.renderTab)
+}
+
+func (r *tabsRenderer) renderTabs(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	if node.(*Tabs).CodeGroup {
+		_, _ = w.WriteString("<div class=\"tabs tabs-code-group\">\n")
+	} else {
+		_, _ = w.WriteString("<div class=\"tabs\">\n")
+	}
+	return ast.WalkContinue, nil
+}
+
+func (r *tabsRenderer) renderTab(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		_, _ = w.WriteString("</div>\n")
+		return ast.WalkContinue, nil
+	}
+
+	var label = html.EscapeString(node.(*Tab).Label)
+	fmt.Fprintf(w, "<div class=\"tabs-panel\" data-tab-label=\"%s\">\n", label)
+	fmt.Fprintf(w, "<p class=\"tabs-label\">%s</p>\n", label)
+	return ast.WalkContinue, nil
+}
@@ -0,0 +1,117 @@
+package doccer
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+)
+
+// Forge describes the URLs of a source code forge.
+// Patterns may contain {repository}, {branch} and {path}.
+type Forge struct {
+	Edit   string // Pattern for the URL to edit a file
+	Source string // Pattern for the URL to view a file
+}
+
+// Forges known by name for repository_edit_url.
+// Gitea patterns also work for Forgejo and Codeberg.
+var Forges = map[string]Forge{
+	"github": {
+		Edit:   "{repository}/edit/{branch}/{path}",
+		Source: "{repository}/blob/{branch}/{path}",
+	},
+	"gitlab": {
+		Edit:   "{repository}/-/edit/{branch}/{path}",
+		Source: "{repository}/-/blob/{branch}/{path}",
+	},
+	"gitea": {
+		Edit:   "{repository}/_edit/{branch}/{path}",
+		Source: "{repository}/src/branch/{branch}/{path}",
+	},
+}
+
+// forge returns the forge configured with repository_edit_url.
+// A value which is not a forge name is used as a custom edit URL pattern.
+func (p *ProjectConfig) forge() (Forge, bool) {
+	if p.RepositoryEditURL == "" {
+		return Forge{}, false
+	}
+
+	if f, ok := Forges[strings.ToLower(p.RepositoryEditURL)]; ok {
+		return f, true
+	}
+
+	return Forge{Edit: p.RepositoryEditURL}, true
+}
+
+// repositoryURL fills in the pattern for the source file of the object.
+// An empty string is returned if the object has no source file.
+func (p *ProjectConfig) repositoryURL(pattern string, obj filesystem.Object) string {
+	if pattern == "" {
+		return ""
+	}
+
+	var file = p.repositoryPath(obj)
+	if file == "" {
+		return ""
+	}
+
+	var branch = p.RepositoryBranch
+	if branch == "" {
+		branch = "main"
+	}
+
+	return strings.NewReplacer(
+		"{repository}", strings.TrimSuffix(p.Repository, "/"),
+		"{branch}", branch,
+		"{path}", file,
+	).Replace(pattern)
+}
+
+// repositoryPath returns the path of the source file of the object inside of the repository.
+// The path is the object's path joined with the input directory or overlay it is read from.
+func (p *ProjectConfig) repositoryPath(obj filesystem.Object) string {
+	var t *filesystem.Template
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		t = o.Index
+	case *filesystem.Template:
+		t = o
+	}
+
+	if t == nil || !t.IsTextFile() {
+		return ""
+	}
+
+	// Archives and absolute paths have no known location inside of the repository
+	var input = filepath.ToSlash(t.Root)
+	if input == "" || path.IsAbs(input) || strings.HasSuffix(strings.ToLower(input), ".zip") {
+		input = "."
+	}
+
+	return path.Join(input, t.Path)
+}
+
+// EditURL returns the URL to edit the source of the object on the forge.
+// It is empty if repository_edit_url is not configured.
+func (c *contextObject) EditURL() string {
+	var project = &c.context.Config.Project
+	var forge, ok = project.forge()
+	if !ok {
+		return ""
+	}
+	return project.repositoryURL(forge.Edit, c.Object)
+}
+
+// SourceURL returns the URL to view the source of the object on the forge.
+// It is only known for the named forges.
+func (c *contextObject) SourceURL() string {
+	var project = &c.context.Config.Project
+	var forge, ok = project.forge()
+	if !ok {
+		return ""
+	}
+	return project.repositoryURL(forge.Source, c.Object)
+}
@@ -0,0 +1,234 @@
+package doccer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/output"
+)
+
+// Name of the manifest written next to fingerprinted assets
+const ASSET_MANIFEST = "manifest.json"
+
+// Directory of the icons in the static assets.
+// Icons are only written to the output when a built file references them.
+const ICONS_DIR = "static/bootstrap-icons"
+
+var (
+	assetAttrRegex = regexp.MustCompile(`\s(href|src)="([^"]*)"`)
+	iconRefRegex   = regexp.MustCompile(`bootstrap-icons/([\w-]+?)(?:\.[0-9a-f]{8})?\.svg`)
+)
+
+func init() {
+	hooks.Register("parse_args", 0, func(d *Doccer, fs *flag.FlagSet) ParseFlagFn {
+		var fingerprint = fs.Bool("fingerprint", false, "add a content hash to the names of static assets")
+		return func(d *Doccer, fs *flag.FlagSet) error {
+			if *fingerprint {
+				d.config.Server.FingerprintAssets = true
+			}
+			return nil
+		}
+	})
+}
+
+// staticOutputDir returns the directory static assets are copied to when building.
+// The directory is relative to the root of the output.
+func (d *Doccer) staticOutputDir() string {
+	var (
+		static = d.config.Server.StaticUrl
+		base   = strings.TrimSuffix(d.config.Server.BaseURL, "/")
+	)
+
+	if base != "" && strings.HasPrefix(static, base+"/") {
+		static = strings.TrimPrefix(static, base)
+	}
+
+	return strings.Trim(static, "/")
+}
+
+// copyStatic copies the static assets and the generated highlighting stylesheet to the sink.
+//
+// If fingerprinting is enabled the content hash is added to the file names,
+// the manifest of the original names is written by writeIcons.
+func (d *Doccer) copyStatic(sink output.Sink) error {
+	var (
+		dir      = d.staticOutputDir()
+		manifest map[string]string
+	)
+
+	if d.config.Server.FingerprintAssets {
+		manifest = make(map[string]string)
+	}
+
+	var write = func(p string, b []byte) error {
+		if manifest != nil {
+			var name = fingerprintName(p, b)
+			manifest[p] = name
+			p = name
+		}
+
+		return sink.WriteFile(path.Join(dir, p), b)
+	}
+
+	var err = fs.WalkDir(d.embedFS, "static", func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() {
+			return err
+		}
+
+		b, err := fs.ReadFile(d.embedFS, p)
+		if err != nil {
+			return err
+		}
+
+		// Icons are written by writeIcons, only their fingerprinted name is needed here
+		if isIcon(p) {
+			if manifest != nil {
+				manifest[p] = fingerprintName(p, b)
+			}
+			return nil
+		}
+
+		return write(p, b)
+	})
+	if err == nil {
+		var css []byte
+		if css, err = d.chromaCSS(true); err == nil {
+			err = write(CHROMA_CSS, css)
+		}
+	}
+	if err != nil {
+		manifest = nil
+	}
+
+	d.assets = manifest
+	return err
+}
+
+// isIcon reports if the static asset is one of the icons
+func isIcon(name string) bool {
+	return path.Dir(name) == ICONS_DIR && path.Ext(name) == ".svg"
+}
+
+// iconSink records the icons referenced by the files written to the sink
+type iconSink struct {
+	output.Sink
+	icons map[string]bool
+}
+
+func (s *iconSink) WriteFile(name string, data []byte) error {
+	for _, match := range iconRefRegex.FindAllSubmatch(data, -1) {
+		s.icons[string(match[1])] = true
+	}
+	return s.Sink.WriteFile(name, data)
+}
+
+// writeIcons writes the icons referenced by the built files to the sink.
+// If manifest is true the manifest of the fingerprinted assets is written as well,
+// it only lists the icons which were written.
+func (d *Doccer) writeIcons(sink *iconSink, manifest bool) error {
+	var (
+		dir     = d.staticOutputDir()
+		written = make(map[string]bool)
+	)
+
+	for name := range sink.icons {
+		var p = path.Join(ICONS_DIR, name+".svg")
+		var b, err = fs.ReadFile(d.embedFS, p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err = sink.Sink.WriteFile(path.Join(dir, d.assetName(p)), b); err != nil {
+			return err
+		}
+		written[p] = true
+	}
+
+	if !manifest || d.assets == nil {
+		return nil
+	}
+
+	var assets = make(map[string]string, len(d.assets))
+	for name, fingerprinted := range d.assets {
+		if !isIcon(name) || written[name] {
+			assets[name] = fingerprinted
+		}
+	}
+
+	b, err := json.MarshalIndent(assets, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return sink.Sink.WriteFile(path.Join(dir, "static", ASSET_MANIFEST), b)
+}
+
+// assetName returns the name of the asset as it is written to the output.
+// Names are only changed when the assets were fingerprinted.
+func (d *Doccer) assetName(name string) string {
+	if d.assets == nil {
+		return name
+	}
+
+	var key = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if fingerprinted, ok := d.assets[key]; ok {
+		return fingerprinted
+	}
+
+	return name
+}
+
+// fingerprintContent rewrites references to static assets in rendered content
+// to the fingerprinted names of the assets.
+func fingerprintContent(context *Context, content string) string {
+	var d = context.Config.Instance
+	if d.assets == nil {
+		return content
+	}
+
+	// Assets may be referenced by the static URL or by their path in the output
+	var prefixes = []string{
+		strings.TrimSuffix(d.config.Server.StaticUrl, "/") + "/",
+		path.Join("/", d.config.Server.BaseURL, d.staticOutputDir()) + "/",
+	}
+
+	return assetAttrRegex.ReplaceAllStringFunc(content, func(s string) string {
+		var match = assetAttrRegex.FindStringSubmatch(s)
+		for _, prefix := range prefixes {
+			if !strings.HasPrefix(match[2], prefix) {
+				continue
+			}
+
+			var name, suffix = match[2][len(prefix):], ""
+			if i := strings.IndexAny(name, "?#"); i >= 0 {
+				name, suffix = name[:i], name[i:]
+			}
+
+			if fingerprinted, ok := d.assets[name]; ok {
+				return fmt.Sprintf(` %s="%s%s%s"`, match[1], prefix, fingerprinted, suffix)
+			}
+		}
+		return s
+	})
+}
+
+// fingerprintName adds a short hash of the content to the file name
+func fingerprintName(name string, content []byte) string {
+	var (
+		hash = sha256.Sum256(content)
+		ext  = path.Ext(name)
+	)
+	return fmt.Sprintf("%s.%s%s", strings.TrimSuffix(name, ext), hex.EncodeToString(hash[:])[:8], ext)
+}
@@ -0,0 +1,335 @@
+package doccer
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/output"
+)
+
+// Directory the tag pages are generated in
+const TAGS_DIR = "tags"
+
+// Tag is a tag used by one or more pages
+type Tag struct {
+	Name   string              // Name of the tag as first used
+	Slug   string              // Name used in the URL of the tag page
+	Pages  []filesystem.Object // Pages with the tag, in the order of the tree
+	Weight int                 // Relative usage of the tag from 1 to 5, for tag clouds
+}
+
+// tagIndex holds the tags of a documentation tree
+type tagIndex struct {
+	mu   sync.Mutex
+	root *filesystem.TemplateDirectory
+	tags []*Tag
+}
+
+// Tags returns all tags used in the documentation sorted by name.
+// The tags are collected once per loaded tree.
+func (d *Doccer) Tags() []*Tag {
+	d.tagIndex.mu.Lock()
+	defer d.tagIndex.mu.Unlock()
+
+	if d.tagIndex.root == d.config.RootDirectory {
+		return d.tagIndex.tags
+	}
+
+	var (
+		tags   = make([]*Tag, 0)
+		bySlug = make(map[string]*Tag)
+	)
+
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		for _, name := range objectTags(obj) {
+			var slug = tagSlug(name)
+			var tag, ok = bySlug[slug]
+			if !ok {
+				tag = &Tag{Name: name, Slug: slug}
+				bySlug[slug] = tag
+				tags = append(tags, tag)
+			}
+			if !slices.Contains(tag.Pages, obj) {
+				tag.Pages = append(tag.Pages, obj)
+			}
+		}
+		return true
+	})
+
+	slices.SortFunc(tags, func(a, b *Tag) int {
+		return strings.Compare(a.Slug, b.Slug)
+	})
+
+	var most = 0
+	for _, tag := range tags {
+		most = max(most, len(tag.Pages))
+	}
+	for _, tag := range tags {
+		tag.Weight = 1 + (len(tag.Pages)-1)*4/max(most-1, 1)
+	}
+
+	d.tagIndex.root = d.config.RootDirectory
+	d.tagIndex.tags = tags
+	return tags
+}
+
+// Tag returns the tag with the slug
+func (d *Doccer) Tag(slug string) (*Tag, bool) {
+	for _, tag := range d.Tags() {
+		if tag.Slug == slug {
+			return tag, true
+		}
+	}
+	return nil, false
+}
+
+// tagURL returns the URL of the tag page, or the tag index if the tag is nil
+func (d *Doccer) tagURL(tag *Tag) string {
+	if tag == nil {
+		return path.Join("/", d.config.Server.BaseURL, TAGS_DIR) + "/"
+	}
+	return path.Join("/", d.config.Server.BaseURL, TAGS_DIR, tag.Slug+".html")
+}
+
+// objectTags returns the tags defined with the Tags directive of the object
+func objectTags(obj filesystem.Object) []string {
+	if c, ok := obj.(*contextObject); ok {
+		obj = c.Object
+	}
+
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		if o.Index != nil {
+			return o.Index.Tags
+		}
+	case *filesystem.Template:
+		return o.Tags
+	}
+	return nil
+}
+
+// tagSlug returns the name of a tag as used in URLs
+func tagSlug(name string) string {
+	var slug = strings.Trim(slugRegex.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if slug == "" {
+		return "tag"
+	}
+	return slug
+}
+
+// ContextTag is a tag bound to the page being rendered
+type ContextTag struct {
+	*Tag
+	URL     string
+	context *Context
+}
+
+// Pages returns the pages with the tag
+func (t *ContextTag) Pages() []filesystem.Object {
+	var pages = make([]filesystem.Object, len(t.Tag.Pages))
+	for i, page := range t.Tag.Pages {
+		pages[i] = makeContextObject(page, t.context)
+	}
+	return pages
+}
+
+func (c *Context) contextTag(tag *Tag) *ContextTag {
+	return &ContextTag{
+		Tag:     tag,
+		URL:     c.relative(c.Config.Instance.tagURL(tag)),
+		context: c,
+	}
+}
+
+// TagCloud returns all tags of the documentation
+func (c *Context) TagCloud() []*ContextTag {
+	var tags = c.Config.Instance.Tags()
+	var cloud = make([]*ContextTag, len(tags))
+	for i, tag := range tags {
+		cloud[i] = c.contextTag(tag)
+	}
+	return cloud
+}
+
+// TagsURL returns the URL of the tag index
+func (c *Context) TagsURL() string {
+	return c.relative(c.Config.Instance.tagURL(nil))
+}
+
+// PagesWithTag returns the pages with the tag
+func (c *Context) PagesWithTag(name string) []filesystem.Object {
+	var tag, ok = c.Config.Instance.Tag(tagSlug(name))
+	if !ok {
+		return nil
+	}
+	return c.contextTag(tag).Pages()
+}
+
+// Tags returns the tags of the object
+func (c *contextObject) Tags() []*ContextTag {
+	var (
+		d     = c.context.Config.Instance
+		names = objectTags(c.Object)
+		tags  = make([]*ContextTag, 0, len(names))
+	)
+	for _, name := range names {
+		if tag, ok := d.Tag(tagSlug(name)); ok {
+			tags = append(tags, c.context.contextTag(tag))
+		}
+	}
+	return tags
+}
+
+func init() {
+	hooks.Register("after_build_sink", 0, func(d *Doccer, c *Config, sink output.Sink) error {
+		return d.buildTagPages(sink)
+	})
+}
+
+// buildTagPages writes the tag index and a page for every tag to the sink
+func (d *Doccer) buildTagPages(sink output.Sink) error {
+	var tags = d.Tags()
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var pages = append([]*Tag{nil}, tags...)
+	for _, tag := range pages {
+		var b strings.Builder
+		var page = d.tagPage(tag, false)
+		if err := d.renderTagPage(&b, page, false); err != nil {
+			return fmt.Errorf("error rendering %s: %s", page.Relative, err)
+		}
+
+		if err := sink.WriteFile(page.Relative, []byte(b.String())); err != nil {
+			return fmt.Errorf("error writing %s: %s", page.Relative, err)
+		}
+	}
+
+	return nil
+}
+
+// checkTagPages returns an error if a generated tag page would be written
+// to the output path of a page of the documentation, or of another tag page.
+func (d *Doccer) checkTagPages() error {
+	var tags = d.Tags()
+	if len(tags) == 0 {
+		return nil
+	}
+
+	var generated = map[string]string{
+		path.Join(TAGS_DIR, "index.html"): "the tag index",
+	}
+	for _, tag := range tags {
+		var name = path.Join(TAGS_DIR, tag.Slug+".html")
+		if other, ok := generated[name]; ok {
+			return fmt.Errorf("the page of tag %q would overwrite %s at %s", tag.Name, other, name)
+		}
+		generated[name] = fmt.Sprintf("the page of tag %q", tag.Name)
+	}
+
+	var err error
+	d.config.RootDirectory.ForEach(func(obj filesystem.Object) bool {
+		var name = d.outputName(obj)
+		if other, ok := generated[name]; ok {
+			err = fmt.Errorf("%s would be overwritten by %s at %s", sourceFile(obj), other, name)
+			return false
+		}
+		return true
+	})
+	return err
+}
+
+// serveTagPage serves the tag index or a tag page.
+// It returns false if the parts do not point to a tag page.
+func (d *Doccer) serveTagPage(w http.ResponseWriter, parts []string) bool {
+	if len(parts) == 0 || len(parts) > 2 || parts[0] != TAGS_DIR {
+		return false
+	}
+
+	var tag *Tag
+	if len(parts) == 2 && parts[1] != "index.html" {
+		var ok bool
+		tag, ok = d.Tag(strings.TrimSuffix(parts[1], ".html"))
+		if !ok {
+			return false
+		}
+	} else if len(d.Tags()) == 0 {
+		return false
+	}
+
+	if err := d.renderTagPage(w, d.tagPage(tag, true), true); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return true
+}
+
+// tagPage returns the page listing the pages of the tag.
+// The tag index is returned if the tag is nil.
+func (d *Doccer) tagPage(tag *Tag, isServing bool) *filesystem.Template {
+	var (
+		b     strings.Builder
+		title = "Tags"
+		name  = "index.html"
+	)
+
+	if tag == nil {
+		b.WriteString("<h1>Tags</h1>\n<ul class=\"tag-list\">\n")
+		for _, t := range d.Tags() {
+			fmt.Fprintf(&b, "<li><a href=\"%s\" class=\"page-tag\">%s</a> (%d)</li>\n",
+				html.EscapeString(d.tagURL(t)), html.EscapeString(t.Name), len(t.Pages))
+		}
+		b.WriteString("</ul>\n")
+	} else {
+		title = fmt.Sprintf("Tag: %s", tag.Name)
+		name = tag.Slug + ".html"
+
+		fmt.Fprintf(&b, "<h1>%s</h1>\n<ul class=\"tag-pages\">\n", html.EscapeString(title))
+		for _, page := range tag.Pages {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n",
+				html.EscapeString(ObjectURL(d.config.Server.BaseURL, page, isServing)), html.EscapeString(page.GetTitle()))
+		}
+		fmt.Fprintf(&b, "</ul>\n<p><a href=\"%s\">All tags</a></p>\n", html.EscapeString(d.tagURL(nil)))
+	}
+
+	var relative = path.Join(TAGS_DIR, name)
+	var page = &filesystem.Template{
+		FSBase: filesystem.FSBase{
+			Name:          name,
+			Path:          relative,
+			Root:          d.config.RootDirectory.Root,
+			Output:        relative,
+			Relative:      relative,
+			Depth:         1,
+			RootDirectory: d.config.RootDirectory,
+		},
+		Content: b.String(),
+	}
+	page.Config = filesystem.NewConfig(&page.FSBase)
+	page.Title = title
+
+	return page
+}
+
+// renderTagPage renders a tag page inside of the base template
+func (d *Doccer) renderTagPage(w io.Writer, page *filesystem.Template, isServing bool) error {
+	var context, err = d.GetContext(isServing)
+	if err != nil {
+		return err
+	}
+	context.setPage(page)
+
+	if err = addTemplateContext(context, page); err != nil {
+		return err
+	}
+
+	return d.executeTemplate(w, "base", context, d.contextFuncs(context))
+}
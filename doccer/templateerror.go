@@ -0,0 +1,176 @@
+package doccer
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"html"
+	"html/template"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+)
+
+// Number of lines shown before and after the line of a template error
+const TEMPLATE_ERROR_CONTEXT = 2
+
+var templatePositionRegex = regexp.MustCompile(`(?s)^template: [^:]*:(\d+)(?::(\d+))?: (?:executing "[^"]*" at )?(.*)$`)
+
+func init() {
+	hooks.Register("parse_args", 0, func(d *Doccer, fs *flag.FlagSet) ParseFlagFn {
+		var strict = fs.Bool("strict", false, "fail the build on template errors instead of showing them on the page")
+		return func(d *Doccer, fs *flag.FlagSet) error {
+			d.buildOptions.Strict = *strict
+			return nil
+		}
+	})
+}
+
+// SourceLine is a line of a source file shown around an error
+type SourceLine struct {
+	Number int    // Line number in the source file
+	Text   string // Content of the line
+	Error  bool   // The error is on this line
+}
+
+// TemplateError is an error in the template of a page, pointing to its location in the source
+type TemplateError struct {
+	File    string       // Source file of the page
+	Line    int          // Line of the error in the source file, 0 if unknown
+	Column  int          // Column of the error in the line, 0 if unknown
+	Message string       // Description of the problem
+	Snippet []SourceLine // Lines around the error
+	Err     error        // Error returned by the template
+}
+
+// newTemplateError returns the error of rendering the template with its location in the source file
+func newTemplateError(t *filesystem.Template, err error) *TemplateError {
+	var e = &TemplateError{
+		File:    sourceFile(t),
+		Message: err.Error(),
+		Err:     err,
+	}
+
+	var match = templatePositionRegex.FindStringSubmatch(err.Error())
+	if match == nil {
+		return e
+	}
+
+	var line, _ = strconv.Atoi(match[1])
+	e.Line = t.ContentLine + line - 1
+	e.Message = match[3]
+
+	// The template position is the offset in the line
+	if match[2] != "" {
+		var offset, _ = strconv.Atoi(match[2])
+		e.Column = offset + 1
+	}
+
+	var lines = strings.Split(t.Source(), "\n")
+	for i := max(line-1-TEMPLATE_ERROR_CONTEXT, 0); i < min(line+TEMPLATE_ERROR_CONTEXT, len(lines)); i++ {
+		e.Snippet = append(e.Snippet, SourceLine{
+			Number: t.ContentLine + i,
+			Text:   lines[i],
+			Error:  i == line-1,
+		})
+	}
+
+	return e
+}
+
+// templateErrorContent returns the content shown in place of a template which failed to render.
+// When serving or building strictly the error is returned instead, so the page fails.
+func templateErrorContent(c *Context, t *filesystem.Template, err error) (string, error) {
+	var tplErr = newTemplateError(t, err)
+	if c.isServing || c.Config.Instance.buildOptions.Strict {
+		return "", tplErr
+	}
+
+	fmt.Printf("warning: %s\n", tplErr)
+	return fmt.Sprintf("error rendering template: %s", template.HTMLEscapeString(tplErr.Error())), nil
+}
+
+func (e *TemplateError) Error() string {
+	switch {
+	case e.Line > 0 && e.Column > 0:
+		return fmt.Sprintf("%s:%d:%d: %s", e.File, e.Line, e.Column, e.Message)
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	}
+	return fmt.Sprintf("%s: %s", e.File, e.Message)
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// Source returns the lines around the error for printing in a terminal.
+// The line of the error is marked, with the column pointed at below it.
+func (e *TemplateError) Source() string {
+	var (
+		b     strings.Builder
+		width = len(strconv.Itoa(e.Line + TEMPLATE_ERROR_CONTEXT))
+	)
+
+	for _, line := range e.Snippet {
+		var marker = " "
+		if line.Error {
+			marker = ">"
+		}
+		fmt.Fprintf(&b, "%s %*d | %s\n", marker, width, line.Number, line.Text)
+
+		if line.Error && e.Column > 0 && e.Column <= len(line.Text)+1 {
+			// Keep tabs so the pointer lines up with the text
+			var indent = strings.Map(func(r rune) rune {
+				if r == '\t' {
+					return r
+				}
+				return ' '
+			}, line.Text[:e.Column-1])
+			fmt.Fprintf(&b, "  %*s | %s^\n", width, "", indent)
+		}
+	}
+
+	return b.String()
+}
+
+// TemplateErrors holds the template errors of all pages which failed to render
+type TemplateErrors []*TemplateError
+
+func (e TemplateErrors) Error() string {
+	var lines = make([]string, 0, len(e)+1)
+	if len(e) == 1 {
+		lines = append(lines, "template error:")
+	} else {
+		lines = append(lines, fmt.Sprintf("%d template errors:", len(e)))
+	}
+	for _, err := range e {
+		lines = append(lines, "  "+err.Error())
+		for _, line := range strings.Split(strings.TrimSuffix(err.Source(), "\n"), "\n") {
+			if line != "" {
+				lines = append(lines, "    "+line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// serveError writes the error of rendering a page.
+// Template errors are shown on a page with the failing lines of the source.
+func (d *Doccer) serveError(w http.ResponseWriter, err error) {
+	var tplErr *TemplateError
+	if !errors.As(err, &tplErr) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusInternalServerError)
+	if err := d.executeTemplate(w, "error", tplErr, nil); err != nil {
+		fmt.Fprintf(w, "<pre>%s</pre>", html.EscapeString(tplErr.Error()))
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/Nigel2392/doccer/doccer/filesystem"
@@ -37,24 +38,82 @@ func IsLocal(filepath string) bool {
 	return err != nil || u.Scheme == ""
 }
 
+// DoccerFS is the filesystem for templates and static files.
+// Files in Overrides take precedence over the files in the embedded FS.
 type DoccerFS struct {
 	fs.FS
+
+	// Overrides for the theme, nil if not overridden
+	Overrides fs.FS
+}
+
+// themeFS returns the theme with the templates and static files at its root.
+// Themes laid out as the assets directory of the repository are read from inside of it.
+func themeFS(theme fs.FS) fs.FS {
+	if _, err := fs.Stat(theme, "templates"); err == nil {
+		return theme
+	}
+
+	if _, err := fs.Stat(theme, "assets/templates"); err == nil {
+		if sub, err := fs.Sub(theme, "assets"); err == nil {
+			return sub
+		}
+	}
+
+	return theme
 }
 
 func (d *DoccerFS) Open(name string) (f fs.File, err error) {
-	cwd, err := os.Getwd()
-	if err != nil {
-		return nil, err
+	if d.Overrides != nil {
+		if f, err = d.Overrides.Open(name); err == nil {
+			return f, nil
+		}
 	}
 
-	var doccerPath = filepath.Join(cwd, DOCCER_DIR, name)
-	if _, err = os.Stat(doccerPath); err == nil {
-		f, err = os.Open(doccerPath)
-	} else {
-		f, err = d.FS.Open(path.Join("assets", name))
+	return d.FS.Open(name)
+}
+
+// ReadDir reads the merged entries of a directory in the overrides and the embedded FS
+func (d *DoccerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if d.Overrides == nil {
+		return fs.ReadDir(d.FS, name)
+	}
+	return filesystem.Overlay(d.Overrides, d.FS).ReadDir(name)
+}
+
+// relativeURL returns the path of target relative to the directory of the page at from.
+// Both paths must be root-absolute, query strings and fragments of the target are kept.
+func relativeURL(from, target string) string {
+	var suffix string
+	if i := strings.IndexAny(target, "?#"); i >= 0 {
+		target, suffix = target[:i], target[i:]
 	}
 
-	return f, err
+	if strings.HasSuffix(target, "/") {
+		target += "index.html"
+	}
+
+	var (
+		fromParts   = strings.Split(strings.Trim(path.Dir(path.Clean(from)), "/"), "/")
+		targetParts = strings.Split(strings.Trim(path.Clean(target), "/"), "/")
+	)
+
+	if len(fromParts) == 1 && fromParts[0] == "" {
+		fromParts = fromParts[:0]
+	}
+
+	var i = 0
+	for i < len(fromParts) && i < len(targetParts)-1 && fromParts[i] == targetParts[i] {
+		i++
+	}
+
+	var rel = make([]string, 0, len(fromParts)-i+len(targetParts)-i)
+	for j := i; j < len(fromParts); j++ {
+		rel = append(rel, "..")
+	}
+	rel = append(rel, targetParts[i:]...)
+
+	return strings.Join(rel, "/") + suffix
 }
 
 func ObjectURL(baseURL string, obj filesystem.Object, isServing bool) string {
@@ -165,17 +224,55 @@ func buildMapFunc[T *filesystem.TemplateDirectory | *filesystem.Template](contex
 	}
 }
 
-func addTemplateContext(context *Context, t *filesystem.Template) {
+func addTemplateContext(context *Context, t *filesystem.Template) error {
 	var (
 		b bytes.Buffer
-		f = context.Config.Instance.TemplateFuncs()
+		d = context.Config.Instance
+		f = d.contextFuncs(context)
 	)
+
+	f["Include"], f["Snippet"] = d.includeFuncs(t)
+	context.object = t
+
+	// Files which are no longer included are not dependencies anymore
+	d.includes.clear(t)
+
+	// Broken references fail the page, pointing to their location in the source
+	if err := d.checkRefs(t); err != nil {
+		return err
+	}
+
+	// Template errors are shown on the page, unless serving or building strictly
 	if err := t.Render(&b, f, context); err != nil {
-		context.Content = template.HTML(
-			fmt.Sprintf("error rendering template: %s", err),
-		)
-		return
+		var content, tplErr = templateErrorContent(context, t, err)
+		context.Content = template.HTML(content)
+		return tplErr
 	}
-	context.Content = template.HTML(b.String())
-	context.object = t
+
+	var content, err = resolveIncludes(context, b.String(), []string{t.Path})
+	if err != nil {
+		return err
+	}
+
+	content, err = resolveRefLinks(context, content)
+	if err != nil {
+		return err
+	}
+
+	context.Content = template.HTML(relativeContent(context, fingerprintContent(context, content)))
+	return nil
+}
+
+var linkAttrRegex = regexp.MustCompile(`\s(href|src)="(/[^/"][^"]*)"`)
+
+// relativeContent rewrites root-absolute links in rendered content relative to the page
+func relativeContent(context *Context, content string) string {
+	if context.pageURL == "" {
+		return content
+	}
+
+	return linkAttrRegex.ReplaceAllStringFunc(content, func(s string) string {
+		var match = linkAttrRegex.FindStringSubmatch(s)
+		return fmt.Sprintf(` %s="%s"`, match[1], context.relative(match[2]))
+	})
 }
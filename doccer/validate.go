@@ -0,0 +1,110 @@
+package doccer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"gopkg.in/yaml.v3"
+)
+
+// validator gathers validation errors for the configuration.
+// Lines are looked up in the parsed document of the config file when available.
+type validator struct {
+	file string
+	node *yaml.Node
+	errs ValidationErrors
+}
+
+// addf adds a validation error for the key
+func (v *validator) addf(key string, format string, args ...any) {
+	v.errs = append(v.errs, &ValidationError{
+		File:    v.file,
+		Line:    v.line(key),
+		Key:     key,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// err returns the gathered errors, nil if there are none
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// line returns the line of the key in the config file.
+// If the key itself is not defined the line of the closest parent is returned.
+func (v *validator) line(key string) int {
+	if v.node == nil {
+		return 0
+	}
+
+	var node = v.node
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	var line = 0
+	for _, part := range strings.Split(key, ".") {
+		var next *yaml.Node
+		switch node.Kind {
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == part {
+					line = node.Content[i].Line
+					next = node.Content[i+1]
+					break
+				}
+			}
+		case yaml.SequenceNode:
+			var idx, err = strconv.Atoi(part)
+			if err == nil && idx >= 0 && idx < len(node.Content) {
+				next = node.Content[idx]
+				line = next.Line
+			}
+		}
+
+		if next == nil {
+			return line
+		}
+		node = next
+	}
+
+	return line
+}
+
+// validateMenu checks that the menu items point to existing objects
+// and are not nested deeper than MAX_MENU_ITEMS_DEPTH.
+func (v *validator) validateMenu(items []MenuItem, dir *filesystem.TemplateDirectory, key string, depth int) {
+	for i, item := range items {
+		var itemKey = fmt.Sprintf("%s.%d", key, i)
+
+		if IsLocal(item.URL) {
+			var parts = []string{}
+			if item.URL != "" {
+				parts = strings.Split(item.URL, "/")
+			}
+			if _, ok := dir.Walk(parts); !ok {
+				v.addf(itemKey+".path", "menu item not found: %s", item.URL)
+			}
+		}
+
+		if len(item.Items) == 0 {
+			continue
+		}
+
+		if depth > MAX_MENU_ITEMS_DEPTH {
+			var name = item.Name
+			if name == "" {
+				name = item.URL
+			}
+			v.addf(itemKey+".items", "menu item %s has too many levels: %d > %d", name, depth, MAX_MENU_ITEMS_DEPTH)
+			continue
+		}
+
+		v.validateMenu(item.Items, dir, itemKey+".items", depth+1)
+	}
+}
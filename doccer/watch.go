@@ -0,0 +1,484 @@
+package doccer
+
+import (
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nigel2392/doccer/doccer/filesystem"
+	"github.com/Nigel2392/doccer/doccer/hooks"
+	"github.com/Nigel2392/doccer/doccer/output"
+	"github.com/Nigel2392/doccer/doccer/render"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Default interval between checks for changes when watching
+	WATCH_POLL_INTERVAL = 500 * time.Millisecond
+
+	// Time without changes before rebuilding, so a burst of saves is built once
+	WATCH_DEBOUNCE = 200 * time.Millisecond
+)
+
+func init() {
+	hooks.Register("parse_args", 0, func(d *Doccer, fs *flag.FlagSet) ParseFlagFn {
+		var (
+			watch = fs.Bool("watch", false, "rebuild the documentation when it changes")
+			poll  = fs.Duration("poll", WATCH_POLL_INTERVAL, "interval between checks for changes when watching")
+		)
+		return func(d *Doccer, fs *flag.FlagSet) error {
+			d.buildOptions.Watch = *watch
+			d.buildOptions.Poll = *poll
+			return nil
+		}
+	})
+}
+
+// fileState is the state of a watched file, the file changed if it differs between polls
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Watch builds the documentation and rebuilds it whenever the input, the templates
+// and static files in the .doccer directory, the config or any included file changes.
+//
+// Changes are found by polling. Only the changed pages and the pages including them are rebuilt,
+// unless the navigation or headings referenced by other pages may have changed. Watch only returns if the first build cannot be started.
+func (d *Doccer) Watch() error {
+	var interval = d.buildOptions.Poll
+	if interval <= 0 {
+		interval = WATCH_POLL_INTERVAL
+	}
+
+	var start = time.Now()
+	var written, err = d.build(nil)
+	if err != nil {
+		watchLog("Build failed after %s:\n%s", elapsed(start), err)
+	} else {
+		watchLog("Built %s in %s", fileCount(written), elapsed(start))
+	}
+
+	watchLog("Watching %s for changes", strings.Join(d.watchRoots(), ", "))
+
+	var snapshot = d.watchSnapshot()
+	for {
+		time.Sleep(interval)
+
+		var current = d.watchSnapshot()
+		var changed = changedFiles(snapshot, current)
+		if len(changed) == 0 {
+			continue
+		}
+
+		// Wait for a burst of saves to settle before rebuilding
+		for {
+			time.Sleep(WATCH_DEBOUNCE)
+			var next = d.watchSnapshot()
+			var more = changedFiles(current, next)
+			if len(more) == 0 {
+				break
+			}
+			changed = append(changed, more...)
+			current = next
+		}
+
+		slices.Sort(changed)
+		changed = slices.Compact(changed)
+		snapshot = current
+
+		start = time.Now()
+		var written, all, err = d.rebuild(changed)
+		switch {
+		case err != nil:
+			watchLog("%s, rebuild failed after %s:\n%s", describeChanges(changed), elapsed(start), err)
+		case all:
+			watchLog("%s, rebuilt all %s in %s", describeChanges(changed), fileCount(written), elapsed(start))
+		default:
+			watchLog("%s, rebuilt %s in %s", describeChanges(changed), fileCount(written), elapsed(start))
+		}
+
+		// Files included by the rebuilt pages are watched from now on,
+		// files which are no longer included are not watched anymore
+		var latest = d.watchSnapshot()
+		for file, state := range latest {
+			if _, ok := snapshot[file]; !ok {
+				snapshot[file] = state
+			}
+		}
+		for file := range snapshot {
+			if _, ok := latest[file]; !ok && !d.isWatched(file) {
+				delete(snapshot, file)
+			}
+		}
+	}
+}
+
+// rebuild reloads the documentation and builds the outputs affected by the changed files.
+// It returns the number of files written and if all pages were rebuilt.
+func (d *Doccer) rebuild(changed []string) (written int, all bool, err error) {
+	var (
+		previous     = d.config.RootDirectory
+		headings     = d.referencedHeadings()
+		reloadConfig bool
+		modified     = make([]string, 0, len(changed))
+		pages        = make(map[string]bool)
+	)
+
+	// Archives are written as a whole
+	all = output.IsArchive(d.config.Project.OutputDirectory)
+
+	for _, file := range changed {
+		for _, p := range d.Dependents(file) {
+			pages[p] = true
+		}
+
+		if p, ok := d.inputPath(file); ok {
+			// An input archive changed, every page may be different
+			if p == "." {
+				all = true
+			}
+			modified = append(modified, p)
+			continue
+		}
+
+		switch {
+		case d.configPath != "" && sameFile(file, d.configPath):
+			reloadConfig = true
+			all = true
+		case isWithin(file, DOCCER_DIR):
+			all = true
+		}
+	}
+
+	if reloadConfig {
+		err = d.reload()
+	} else {
+		err = d.config.Init()
+	}
+	if err != nil {
+		return 0, true, err
+	}
+
+	if !all {
+		all = !d.addModified(pages, modified, templatesByPath(previous), templatesByPath(d.config.RootDirectory), headings)
+	}
+
+	if all {
+		written, err = d.build(nil)
+		return written, true, err
+	}
+
+	written, err = d.build(pages)
+	return written, false, err
+}
+
+// addModified adds the modified pages and the pages including them to pages.
+// It returns false if the pages cannot be rebuilt on their own, because pages were added or removed,
+// their title or position in the navigation changed or the headings referenced by other pages changed.
+//
+// The headings are those of the previous tree which were looked up by references.
+func (d *Doccer) addModified(pages map[string]bool, modified []string, before, after map[string]*filesystem.Template, headings map[*filesystem.Template][]render.Heading) bool {
+	if len(before) != len(after) {
+		return false
+	}
+
+	for p := range before {
+		if _, ok := after[p]; !ok {
+			return false
+		}
+	}
+
+	for _, p := range modified {
+		var old, ok = before[p]
+		if !ok {
+			return false
+		}
+
+		var t = after[p]
+		if old.Title != t.Title || old.Order != t.Order || old.Ordered != t.Ordered || !slices.Equal(old.Tags, t.Tags) ||
+			!slices.Equal(old.Next, t.Next) || !slices.Equal(old.Previous, t.Previous) {
+			return false
+		}
+
+		// References to the page on other pages are resolved against its headings
+		if previous, ok := headings[old]; ok && !sameHeadings(previous, d.pageHeadings(t)) {
+			return false
+		}
+
+		pages[p] = true
+	}
+
+	// Pages including a rebuilt page with IncludePage are rebuilt too
+	for found := true; found; {
+		found = false
+		for p, t := range after {
+			if pages[p] {
+				continue
+			}
+
+			for _, match := range includePageRegex.FindAllStringSubmatch(t.Source(), -1) {
+				if included, err := d.lookupPage(match[1]); err == nil && pages[included.Path] {
+					pages[p] = true
+					found = true
+					break
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// referencedHeadings returns the headings of the pages which have been looked up by references
+func (d *Doccer) referencedHeadings() map[*filesystem.Template][]render.Heading {
+	d.headings.mu.Lock()
+	defer d.headings.mu.Unlock()
+
+	var headings = make(map[*filesystem.Template][]render.Heading, len(d.headings.pages))
+	for t, h := range d.headings.pages {
+		headings[t] = h
+	}
+	return headings
+}
+
+// sameHeadings reports if references resolve the same against both lists of headings
+func sameHeadings(a, b []render.Heading) bool {
+	return slices.EqualFunc(a, b, func(a, b render.Heading) bool {
+		return a.ID == b.ID && a.Title == b.Title
+	})
+}
+
+// reload reads the config file again and loads the documentation tree.
+// The command line flags are applied to the new config, changes to the enabled features need a restart.
+func (d *Doccer) reload() error {
+	var b, err = os.ReadFile(d.configPath)
+	if err != nil {
+		return err
+	}
+
+	var node yaml.Node
+	if err = yaml.Unmarshal(b, &node); err != nil {
+		return err
+	}
+
+	var config = NewConfig(d)
+	if err = node.Decode(config); err != nil {
+		return err
+	}
+	config.node = &node
+
+	var previous = d.config
+	d.config = config
+	if err = d.ParseArgs(d.args); err == nil {
+		err = config.Init()
+	}
+	if err != nil {
+		d.config = previous
+		return err
+	}
+
+	if !slices.Equal(previous.Features, config.Features) {
+		watchLog("Warning: the enabled features changed, restart to apply the change")
+	}
+
+	return nil
+}
+
+// watchRoots returns the files and directories watched for changes
+func (d *Doccer) watchRoots() []string {
+	var roots = make([]string, 0, len(d.config.Project.Overlays)+4)
+	roots = append(roots, d.config.Project.InputDirectory)
+	roots = append(roots, d.config.Project.Overlays...)
+	roots = append(roots,
+		filepath.Join(DOCCER_DIR, "templates"),
+		filepath.Join(DOCCER_DIR, "static"),
+	)
+	if d.configPath != "" {
+		roots = append(roots, d.configPath)
+	}
+	return roots
+}
+
+// isWatched reports if the file is inside of one of the watched roots
+func (d *Doccer) isWatched(file string) bool {
+	for _, root := range d.watchRoots() {
+		if isWithin(file, root) {
+			return true
+		}
+	}
+	return false
+}
+
+// watchSnapshot returns the state of the watched files and of the files included by templates.
+// The output directory is skipped when it is inside of a watched directory.
+func (d *Doccer) watchSnapshot() map[string]fileState {
+	var (
+		files     = make(map[string]fileState)
+		output, _ = filepath.Abs(d.config.Project.OutputDirectory)
+	)
+
+	for _, root := range d.watchRoots() {
+		_ = filepath.WalkDir(root, func(p string, e fs.DirEntry, err error) error {
+			// Missing roots and files removed while walking are skipped
+			if err != nil {
+				return nil
+			}
+
+			if e.IsDir() {
+				if abs, _ := filepath.Abs(p); abs == output {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info, err := e.Info(); err == nil {
+				files[p] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+
+	d.includes.mu.Lock()
+	defer d.includes.mu.Unlock()
+
+	for file := range d.includes.deps {
+		if info, err := os.Stat(file); err == nil {
+			files[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+
+	return files
+}
+
+// changedFiles returns the files which were added, removed or modified between the snapshots
+func changedFiles(before, after map[string]fileState) []string {
+	var changed = make([]string, 0)
+	for file, state := range after {
+		if old, ok := before[file]; !ok || !old.modTime.Equal(state.modTime) || old.size != state.size {
+			changed = append(changed, file)
+		}
+	}
+	for file := range before {
+		if _, ok := after[file]; !ok {
+			changed = append(changed, file)
+		}
+	}
+	return changed
+}
+
+// inputPath returns the path in the documentation tree of a file in one of the inputs.
+// The path is "." if the file is an input archive.
+func (d *Doccer) inputPath(file string) (string, bool) {
+	for _, root := range append([]string{d.config.Project.InputDirectory}, d.config.Project.Overlays...) {
+		if rel, ok := relativeTo(root, file); ok {
+			return filepath.ToSlash(rel), true
+		}
+	}
+	return "", false
+}
+
+// templatesByPath returns the pages and partials of the tree by the path of their source
+func templatesByPath(root *filesystem.TemplateDirectory) map[string]*filesystem.Template {
+	var templates = make(map[string]*filesystem.Template)
+	var add = func(obj filesystem.Object) bool {
+		switch o := obj.(type) {
+		case *filesystem.TemplateDirectory:
+			if o.Index != nil {
+				templates[o.Index.Path] = o.Index
+			}
+		case *filesystem.Template:
+			templates[o.Path] = o
+		}
+		return true
+	}
+
+	if root == nil {
+		return templates
+	}
+
+	root.ForEach(add)
+	if root.Partials != nil {
+		root.Partials.ForEach(add)
+	}
+
+	return templates
+}
+
+// sourcePath returns the path of the source of the object, the index for directories
+func sourcePath(obj filesystem.Object) string {
+	switch o := obj.(type) {
+	case *filesystem.TemplateDirectory:
+		if o.Index != nil {
+			return o.Index.Path
+		}
+	case *filesystem.Template:
+		return o.Path
+	}
+	return ""
+}
+
+// relativeTo returns the path of the file relative to the root, if it is inside of it
+func relativeTo(root, file string) (string, bool) {
+	var absRoot, err = filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// isWithin reports if the file is the root or inside of it
+func isWithin(file, root string) bool {
+	var _, ok = relativeTo(root, file)
+	return ok
+}
+
+// sameFile reports if both paths point to the same file
+func sameFile(a, b string) bool {
+	var rel, ok = relativeTo(a, b)
+	return ok && rel == "."
+}
+
+// describeChanges returns a short description of the changed files
+func describeChanges(changed []string) string {
+	switch len(changed) {
+	case 1:
+		return fmt.Sprintf("%s changed", changed[0])
+	case 2:
+		return fmt.Sprintf("%s and %s changed", changed[0], changed[1])
+	}
+	return fmt.Sprintf("%s and %d more files changed", changed[0], len(changed)-1)
+}
+
+// fileCount returns the number of files for printing
+func fileCount(n int) string {
+	if n == 1 {
+		return "1 file"
+	}
+	return fmt.Sprintf("%d files", n)
+}
+
+// elapsed returns the time since start, rounded for printing
+func elapsed(start time.Time) time.Duration {
+	return time.Since(start).Round(time.Millisecond)
+}
+
+// watchLog prints a message prefixed with the current time
+func watchLog(format string, args ...any) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("15:04:05"), fmt.Sprintf(format, args...))
+}
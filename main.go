@@ -1,19 +1,22 @@
 package main
 
 import (
-	"embed"
 	"errors"
 	"fmt"
 	"os"
 	"slices"
 	"strings"
 
+	"github.com/Nigel2392/doccer/assets"
 	"github.com/Nigel2392/doccer/doccer"
 )
 
 var LOAD_REQUIRED_COMMANDS = []string{
 	"build",
 	"serve",
+	"export",
+	"lint",
+	"test",
 }
 
 func matchCommand(d *doccer.Doccer, command string, args []string) (err error) {
@@ -35,21 +38,20 @@ func matchCommand(d *doccer.Doccer, command string, args []string) (err error) {
 		return d.Serve()
 	case "init":
 		return d.Init()
+	case "export":
+		return d.Export()
+	case "lint":
+		return d.Lint()
+	case "test":
+		return d.Test()
 	default:
-		return errors.New("command not found, try 'build -h', 'serve -h' or 'init -h'")
+		return errors.New("command not found, try 'build -h', 'serve -h', 'export -h', 'lint -h', 'test -h' or 'init -h'")
 	}
 }
 
-//go:embed assets/*
-//go:embed assets/static/*
-//go:embed assets/static/bootstrap-icons/*.svg
-//go:embed assets/templates/*.tmpl
-//go:embed assets/templates/hooks/*.tmpl
-var embedFS embed.FS
-
 func main() {
 
-	var d, err = doccer.NewDoccer(embedFS, "doccer.yaml")
+	var d, err = doccer.NewDoccer(assets.FS, "doccer.yaml")
 	if err != nil && !errors.Is(err, doccer.ErrNoConfig) {
 		fmt.Println(err)
 		os.Exit(1)